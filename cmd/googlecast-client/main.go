@@ -34,7 +34,7 @@ func WatchEvents(ctx context.Context, client googlecast.Client) error {
 	// Receive error from StreamEvents in the background
 	errs := make(chan error)
 	go func() {
-		errs <- client.StreamEvents(ctx)
+		errs <- client.StreamEvents(ctx, googlecast.SubscribeOptions{})
 	}()
 	// subscribe to events
 	evts := client.Subscribe()
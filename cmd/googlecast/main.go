@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"strings"
@@ -22,11 +23,25 @@ func HandleEvent(cast googlecast.Cast, evt googlecast.Event) error {
 		}
 		fmt.Printf("%-20s %-20s %s\n", event_type, evt.Device().Name(), evt.Device().Id())
 	case googlecast.CAST_EVENT_VOLUME_UPDATED:
-		fmt.Printf("%-20s %-20s %s\n", event_type, evt.Device().Name(), evt.Channel().Volume())
+		fmt.Printf("%-20s %-20s %s\n", event_type, evt.Device().Name(), evt.State().Volume())
 	case googlecast.CAST_EVENT_APPLICATION_UPDATED:
-		fmt.Printf("%-20s %-20s %s\n", event_type, evt.Device().Name(), evt.Channel().Application())
+		fmt.Printf("%-20s %-20s %s\n", event_type, evt.Device().Name(), evt.State().Application())
+	case googlecast.CAST_EVENT_SESSION_STARTED, googlecast.CAST_EVENT_SESSION_TAKEN_OVER:
+		fmt.Printf("%-20s %-20s %s\n", event_type, evt.Device().Name(), evt.State().Application().Name())
+	case googlecast.CAST_EVENT_SESSION_ENDED:
+		fmt.Printf("%-20s %-20s %s\n", event_type, evt.Device().Name(), evt.Previous().Application().Name())
+	case googlecast.CAST_EVENT_INPUT_UPDATED:
+		fmt.Printf("%-20s %-20s active_input=%v\n", event_type, evt.Device().Name(), evt.State().ActiveInput())
+	case googlecast.CAST_EVENT_STANDBY_UPDATED:
+		fmt.Printf("%-20s %-20s standby=%v\n", event_type, evt.Device().Name(), evt.State().StandBy())
 	case googlecast.CAST_EVENT_MEDIA_UPDATED:
-		fmt.Printf("%-20s %-20s %s\n", event_type, evt.Device().Name(), evt.Channel().Media())
+		fmt.Printf("%-20s %-20s %s\n", event_type, evt.Device().Name(), evt.State().Media())
+	case googlecast.CAST_EVENT_GROUP_UPDATED, googlecast.CAST_EVENT_GROUP_MEMBER_ADDED, googlecast.CAST_EVENT_GROUP_MEMBER_UPDATED, googlecast.CAST_EVENT_GROUP_MEMBER_REMOVED:
+		if evt_, ok := evt.(googlecast.GroupEvent); ok {
+			for _, member := range evt_.Members() {
+				fmt.Printf("%-20s %-20s %s\n", event_type, evt.Device().Name(), member.Name())
+			}
+		}
 	}
 	return nil
 }
@@ -46,29 +61,43 @@ func Main(app *gopi.AppInstance, done chan<- struct{}) error {
 func Events(app *gopi.AppInstance, start chan<- struct{}, stop <-chan struct{}) error {
 	cast := app.ModuleInstance("googlecast").(googlecast.Cast)
 	timeout, _ := app.AppFlags.GetDuration("timeout")
-	timer := time.NewTimer(timeout)
 	start <- gopi.DONE
 
-	// If there is an argument, then this is the service to lookup
-	events := cast.Subscribe()
+	// Subscribe to events, dropping the oldest events if the
+	// output falls behind
+	events := cast.SubscribeEvents(googlecast.SubscribeOptions{
+		Overflow: googlecast.CAST_OVERFLOW_DROP_OLDEST,
+	})
+
+	// Perform a lookup in the background, and quit after the timeout
+	// once the lookup has completed
+	var quit <-chan time.Time
+	refreshed := make(chan error, 1)
+	go func() {
+		refreshed <- cast.Refresh(context.Background())
+	}()
 FOR_LOOP:
 	for {
 		select {
-		case <-timer.C:
+		case err := <-refreshed:
+			if err != nil {
+				app.Logger.Error("Error: %v", err)
+			}
+			quit = time.After(timeout)
+		case <-quit:
 			// Quit
 			app.SendSignal()
 		case evt := <-events:
-			if evt_, ok := evt.(googlecast.Event); ok {
-				if err := HandleEvent(cast, evt_); err != nil {
-					app.Logger.Error("Error: %v", err)
-				}
+			if evt == nil {
+				break FOR_LOOP
+			} else if err := HandleEvent(cast, evt); err != nil {
+				app.Logger.Error("Error: %v", err)
 			}
 		case <-stop:
-			timer.Stop()
 			break FOR_LOOP
 		}
 	}
-	cast.Unsubscribe(events)
+	cast.UnsubscribeEvents(events)
 	return nil
 }
 
@@ -79,7 +108,7 @@ func main() {
 	config := gopi.NewAppConfig("googlecast", "discovery")
 
 	// Set timeout flag
-	config.AppFlags.FlagDuration("timeout", time.Second*2, "Timeout for discovery")
+	config.AppFlags.FlagDuration("timeout", time.Second*2, "Timeout for events after discovery")
 
 	// Run the command line tool
 	os.Exit(gopi.CommandLineTool2(config, Main, Events))
@@ -10,6 +10,10 @@ package googlecast
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
 	"time"
 
 	// Frameworks
@@ -20,9 +24,46 @@ import (
 // TYPES
 
 type (
-	EventType uint
+	EventType        uint
+	DeviceCapability uint
+	MediaCommand     uint
+	StreamType       uint
+	PlayerState      uint
+	OverflowPolicy   uint
+	ReplayMode       uint
 )
 
+// DeviceQuery matches discovered devices. Empty fields match any
+// device, the name may be an exact name or a glob pattern, and all
+// capabilities set must be supported by the device
+type DeviceQuery struct {
+	Id           string
+	Name         string
+	Model        string
+	Capabilities DeviceCapability
+}
+
+// SubscribeOptions filters events for a subscription. Empty fields
+// match any event, and device identifiers and names may be exact
+// values or glob patterns. A subscription has a buffer for events,
+// and the overflow policy determines what happens when the buffer
+// is full. A subscription which is closed on overflow ends with an
+// ErrorEvent for ErrOverflow.
+//
+// A subscription can start with events for the current state, or
+// with retained events after a sequence number. When retained events
+// have been discarded, the first sequence number replayed is later
+// than expected
+type SubscribeOptions struct {
+	Types     []EventType
+	DeviceIds []string
+	Names     []string
+	Buffer    int
+	Overflow  OverflowPolicy
+	Replay    ReplayMode
+	Sequence  uint64
+}
+
 ////////////////////////////////////////////////////////////////////////////////
 // CONSTANTS
 
@@ -36,18 +77,138 @@ const (
 	CAST_EVENT_VOLUME_UPDATED
 	CAST_EVENT_APPLICATION_UPDATED
 	CAST_EVENT_MEDIA_UPDATED
+	CAST_EVENT_GROUP_UPDATED
+	CAST_EVENT_GROUP_MEMBER_ADDED
+	CAST_EVENT_GROUP_MEMBER_UPDATED
+	CAST_EVENT_GROUP_MEMBER_REMOVED
+	CAST_EVENT_INPUT_UPDATED
+	CAST_EVENT_STANDBY_UPDATED
+	CAST_EVENT_MEDIA_PLAYER_STATE_UPDATED
+	CAST_EVENT_MEDIA_CONTENT_UPDATED
+	CAST_EVENT_MEDIA_QUEUE_UPDATED
+	CAST_EVENT_MEDIA_TRACKS_UPDATED
+	CAST_EVENT_MEDIA_POSITION_JUMPED
+	CAST_EVENT_SESSION_STARTED
+	CAST_EVENT_SESSION_ENDED
+	CAST_EVENT_SESSION_TAKEN_OVER
+)
+
+const (
+	CAST_CAPABILITY_NONE      DeviceCapability = 0
+	CAST_CAPABILITY_VIDEO_OUT DeviceCapability = (1 << (iota - 1)) // Video output
+	CAST_CAPABILITY_VIDEO_IN                                       // Video input
+	CAST_CAPABILITY_AUDIO_OUT                                      // Audio output
+	CAST_CAPABILITY_AUDIO_IN                                       // Audio input
+	CAST_CAPABILITY_DEV_MODE                                       // Development mode
+	CAST_CAPABILITY_MULTIZONE                                      // Multizone group
+	CAST_CAPABILITY_MIN       = CAST_CAPABILITY_VIDEO_OUT
+	CAST_CAPABILITY_MAX       = CAST_CAPABILITY_MULTIZONE
+)
+
+const (
+	CAST_MEDIA_COMMAND_NONE          MediaCommand = 0
+	CAST_MEDIA_COMMAND_PAUSE         MediaCommand = (1 << (iota - 1)) // Pause
+	CAST_MEDIA_COMMAND_SEEK                                           // Seek
+	CAST_MEDIA_COMMAND_STREAM_VOLUME                                  // Stream volume
+	CAST_MEDIA_COMMAND_STREAM_MUTE                                    // Stream mute
+	CAST_MEDIA_COMMAND_SKIP_FORWARD                                   // Skip forward
+	CAST_MEDIA_COMMAND_SKIP_BACKWARD                                  // Skip backward
+	CAST_MEDIA_COMMAND_QUEUE_NEXT                                     // Next item in queue
+	CAST_MEDIA_COMMAND_QUEUE_PREV                                     // Previous item in queue
+	CAST_MEDIA_COMMAND_MIN           = CAST_MEDIA_COMMAND_PAUSE
+	CAST_MEDIA_COMMAND_MAX           = CAST_MEDIA_COMMAND_QUEUE_PREV
+)
+
+const (
+	CAST_PLAYER_STATE_NONE PlayerState = iota
+	CAST_PLAYER_STATE_IDLE
+	CAST_PLAYER_STATE_PLAYING
+	CAST_PLAYER_STATE_PAUSED
+	CAST_PLAYER_STATE_BUFFERING
+)
+
+const (
+	CAST_OVERFLOW_DROP_OLDEST OverflowPolicy = iota // Discard the oldest buffered event
+	CAST_OVERFLOW_DROP_NEWEST                       // Discard the new event
+	CAST_OVERFLOW_BLOCK                             // Wait for the subscriber
+	CAST_OVERFLOW_CLOSE                             // End the subscription
+)
+
+const (
+	CAST_REPLAY_NONE     ReplayMode = iota // Only new events
+	CAST_REPLAY_STATE                      // Events for the current state, then new events
+	CAST_REPLAY_SEQUENCE                   // Retained events after a sequence number (or the current state when not retained), then new events
+)
+
+const (
+	CAST_STREAM_TYPE_NONE     StreamType = iota
+	CAST_STREAM_TYPE_BUFFERED            // Content with a known duration
+	CAST_STREAM_TYPE_LIVE                // Live stream, which may have a seekable range
 )
 
+////////////////////////////////////////////////////////////////////////////////
+// ERRORS
+
+var (
+	ErrManagementDisabled   = errors.New("Device management is not enabled")
+	ErrUnsupportedNamespace = errors.New("Namespace not supported by application")
+	ErrOverflow             = errors.New("Subscription buffer overflow")
+)
+
+// UnsupportedCommandError is returned by media controls when the
+// receiver does not support the command for the current media
+type UnsupportedCommandError struct {
+	Command MediaCommand
+}
+
 ////////////////////////////////////////////////////////////////////////////////
 // INTERFACES
 
 type Cast interface {
 	gopi.Driver
-	gopi.Publisher
 
-	// Return list of discovered Google Chromecast Devices
+	// Return list of discovered Google Chromecast Devices. Devices
+	// loaded from the cache are not returned by this or other lookups
+	// until they are seen on the network and added
 	Devices() []Device
 
+	// Perform an immediate lookup of devices, returning when
+	// the lookup has completed
+	Refresh(context.Context) error
+
+	// Return a device by identifier or nil if not found
+	DeviceById(string) Device
+
+	// Return devices with an exact name or a name which matches a
+	// glob pattern, devices with a model name, or devices which
+	// support all the capabilities
+	DevicesByName(string) []Device
+	DevicesByModel(string) []Device
+	DevicesByCapability(DeviceCapability) []Device
+
+	// Return devices which match a query
+	Query(DeviceQuery) []Device
+
+	// Block until a device matching a query is discovered, or the
+	// context is cancelled
+	WaitForDevice(context.Context, DeviceQuery) (Device, error)
+
+	// Device management, which returns ErrManagementDisabled unless
+	// management has been enabled. A factory reset requires the
+	// device identifier as confirmation. Requests use the setup API
+	// without authorization, which current firmware may refuse
+	Reboot(context.Context, Device) error
+	FactoryReset(ctx context.Context, device Device, confirm string) error
+	SetName(context.Context, Device, string) error
+	SetTimezone(context.Context, Device, string) error
+	SetLocale(context.Context, Device, string) error
+
+	// Subscribe to events which match a filter, and unsubscribe,
+	// which closes the channel. Events are only delivered to
+	// subscribers, which never block the emitter
+	SubscribeEvents(SubscribeOptions) <-chan Event
+	UnsubscribeEvents(<-chan Event)
+
 	// Connect to the control channel for a device, with timeout
 	Connect(Device, gopi.RPCFlag, time.Duration) (Channel, error)
 	Disconnect(Channel) error
@@ -59,6 +220,57 @@ type Device interface {
 	Model() string
 	Service() string
 	State() uint
+	Capabilities() DeviceCapability
+
+	// Time the device was first and last seen, and whether
+	// a connected channel is receiving messages from the device
+	FirstSeen() time.Time
+	LastSeen() time.Time
+	Reachable() bool
+
+	// Returns false if the device was loaded from a cache
+	// and has not yet been seen on the network
+	Verified() bool
+
+	// Return information from the device setup API
+	Info(context.Context) (DeviceInfo, error)
+}
+
+// DeviceInfo is information returned from the local setup API
+// of a device
+type DeviceInfo interface {
+	Name() string
+
+	// Return the version of the setup API, the cast firmware
+	// revision and the system build number
+	Version() string
+	CastBuildRevision() string
+	BuildVersion() string
+
+	Uptime() time.Duration
+	MacAddress() string
+	Timezone() string
+	Locale() string
+
+	// Network settings
+	IPAddress() net.IP
+	EthernetConnected() bool
+	SSID() string
+	BSSID() string
+	SignalLevel() int
+	NoiseLevel() int
+}
+
+// Group is a cast group of devices which play in sync, and has a
+// leader device which is the one which is connected to
+type Group interface {
+	Device
+
+	// Return member devices which have been discovered
+	Members() []Device
+
+	// Return the leader device, or nil if not discovered
+	Leader() Device
 }
 
 type Channel interface {
@@ -70,31 +282,109 @@ type Channel interface {
 	Volume() Volume
 	Media() Media
 
-	/*
-		// Set Properties
-		SetApplication(Application) error // Application to watch or nil
-		SetPlay(bool) (int, error)        // Play or stop
-		SetPause(bool) (int, error)       // Pause or play
-		SetVolume(float32) (int, error)   // Set volume level
-		SetMuted(bool) (int, error)       // Set muted
-		//SetTrackNext() (int, error)
-		//SetTrackPrev() (int, error)
-		//StreamUrl(string)
-	*/
+	// Return true if the device is the active input (for example, the
+	// selected HDMI input on a TV) and true if the device is in standby
+	ActiveInput() bool
+	StandBy() bool
+
+	// Return availability of applications which can be launched
+	AppAvailability(ctx context.Context, appIds ...string) (map[string]bool, error)
+
+	// Load media into the current application
+	LoadMedia(MediaRequest) (int, error)
+
+	// Media controls, which return an *UnsupportedCommandError when
+	// the receiver does not support the command for the current media
+	SetPlay(bool) (int, error)            // Play or stop
+	SetPause(bool) (int, error)           // Pause or play
+	SetSeek(float32) (int, error)         // Seek to absolute position in seconds
+	SetSkip(float32) (int, error)         // Skip forward or backward in seconds
+	SetStreamVolume(float32) (int, error) // Set stream volume level
+	SetStreamMuted(bool) (int, error)     // Set stream muted
+	SetTrackNext() (int, error)           // Next item in queue
+	SetTrackPrev() (int, error)           // Previous item in queue
+	SetSeekToLive() (int, error)          // Seek to live edge of a live stream
+	SetPlaybackRate(float32) (int, error) // Set playback rate, where 1.0 is normal speed
+
+	// Set active tracks by identifier (or none) and set the
+	// appearance of text tracks
+	SetActiveTracks(...int) (int, error)
+	SetTextTrackStyle(TextTrackStyle) (int, error)
+
+	// Launch an application by identifier, stop the current
+	// application and set the device volume
+	LaunchApp(string) (int, error)
+	StopApp() (int, error)
+	SetVolume(float32) (int, error) // Set volume level, between zero and one
+	SetMuted(bool) (int, error)     // Set muted
 }
 
 type Application interface {
 	ID() string
 	Name() string
 	Status() string
+	Type() string
+	UniversalID() string
+
+	// Return the session identifier, whether the application is the
+	// idle screen, and the time the session was first seen
+	SessionId() string
+	IdleScreen() bool
+	SessionStarted() time.Time
+
+	// Return the namespaces supported by the application, and whether
+	// a namespace is supported
+	Namespaces() []string
+	SupportsNamespace(string) bool
 }
 
 type Volume interface {
 	Level() float32
 	Muted() bool
+
+	// The volume control type is "attenuation", "fixed" or "master"
+	// and the step interval is the change in level for each step
+	ControlType() string
+	StepInterval() float32
 }
 
 type Media interface {
+	// Return the player state, and the reason the player is idle, which
+	// is CANCELLED, INTERRUPTED, FINISHED or ERROR
+	PlayerState() PlayerState
+	IdleReason() string
+
+	// Return the content identifier, content type and metadata, which
+	// is one of the metadata types or nil
+	ContentId() string
+	ContentType() string
+	Metadata() MediaMetadata
+
+	// Return the audio, video and text tracks, the identifiers of
+	// active tracks and the text track style, or nil
+	Tracks() []Track
+	ActiveTrackIds() []int
+	TextTrackStyle() *TextTrackStyle
+
+	// Return the commands supported for the media, and whether
+	// all the commands are supported
+	SupportedCommands() MediaCommand
+	Supports(MediaCommand) bool
+
+	// Return the stream type, and for live streams the seekable range
+	// in seconds and whether the live event has ended
+	StreamType() StreamType
+	LiveSeekableRange() (start, end float32, ok bool)
+	LiveDone() bool
+
+	// Return the number of seconds behind the live edge, or zero if
+	// the media is not a live stream with a seekable range
+	BehindLiveEdge() float32
+
+	// Return the playback rate, and the position in seconds estimated
+	// from the last status received, the player state and playback rate
+	PlaybackRate() float32
+	EstimatedPosition() float32
 }
 
 type Event interface {
@@ -103,6 +393,44 @@ type Event interface {
 	Type() EventType
 	Device() Device
 	Channel() Channel
+
+	// Return the sequence number, which increases for each event
+	// emitted, and the time the event was emitted
+	Sequence() uint64
+	Timestamp() time.Time
+
+	// Return the channel state when the event was emitted and the
+	// state before the change, or nil for device events
+	State() State
+	Previous() State
+}
+
+// State is a snapshot of channel state, which does not change
+// once the event has been emitted
+type State interface {
+	Application() Application
+	Volume() Volume
+	Media() Media
+	ActiveInput() bool
+	StandBy() bool
+}
+
+// GroupEvent is emitted when group membership changes, and identifies
+// the affected member devices
+type GroupEvent interface {
+	Event
+
+	Group() Group
+	Members() []Device
+}
+
+// ErrorEvent is emitted by a remote service when it could not act
+// on an event, for example when a device could not be connected, and
+// ends a subscription which is closed on overflow
+type ErrorEvent interface {
+	Event
+
+	Err() error
 }
 
 ////////////////////////////////////////////////////////////////////////////////
@@ -118,8 +446,33 @@ type Client interface {
 	// Return devices from the remote service
 	Devices() ([]Device, error)
 
-	// Stream discovery events
-	StreamEvents(ctx context.Context) error
+	// Stream events which match the device identifiers, names and
+	// types in the options, resuming from the last event received
+	StreamEvents(ctx context.Context, options SubscribeOptions) error
+
+	// Device management on the remote service
+	Reboot(id string) error
+	FactoryReset(id, confirm string) error
+	SetName(id, name string) error
+	SetTimezone(id, timezone string) error
+	SetLocale(id, locale string) error
+
+	// Connect and disconnect a device on the remote service, and
+	// return the state of a connected device
+	Connect(id string) error
+	Disconnect(id string) error
+	Status(id string) (State, error)
+
+	// Playback control on the remote service
+	Play(id string) error
+	Pause(id string) error
+	Stop(id string) error
+	Seek(id string, position float32) error
+	SetVolume(id string, level float32) error
+	SetMuted(id string, muted bool) error
+	LaunchApp(id, appId string) error
+	StopApp(id string) error
+	LoadMedia(id string, request MediaRequest) error
 }
 
 ////////////////////////////////////////////////////////////////////////////////
@@ -145,7 +498,169 @@ func (t EventType) String() string {
 		return "CAST_EVENT_APPLICATION_UPDATED"
 	case CAST_EVENT_MEDIA_UPDATED:
 		return "CAST_EVENT_MEDIA_UPDATED"
+	case CAST_EVENT_GROUP_UPDATED:
+		return "CAST_EVENT_GROUP_UPDATED"
+	case CAST_EVENT_GROUP_MEMBER_ADDED:
+		return "CAST_EVENT_GROUP_MEMBER_ADDED"
+	case CAST_EVENT_GROUP_MEMBER_UPDATED:
+		return "CAST_EVENT_GROUP_MEMBER_UPDATED"
+	case CAST_EVENT_GROUP_MEMBER_REMOVED:
+		return "CAST_EVENT_GROUP_MEMBER_REMOVED"
+	case CAST_EVENT_INPUT_UPDATED:
+		return "CAST_EVENT_INPUT_UPDATED"
+	case CAST_EVENT_STANDBY_UPDATED:
+		return "CAST_EVENT_STANDBY_UPDATED"
+	case CAST_EVENT_MEDIA_PLAYER_STATE_UPDATED:
+		return "CAST_EVENT_MEDIA_PLAYER_STATE_UPDATED"
+	case CAST_EVENT_MEDIA_CONTENT_UPDATED:
+		return "CAST_EVENT_MEDIA_CONTENT_UPDATED"
+	case CAST_EVENT_MEDIA_QUEUE_UPDATED:
+		return "CAST_EVENT_MEDIA_QUEUE_UPDATED"
+	case CAST_EVENT_MEDIA_TRACKS_UPDATED:
+		return "CAST_EVENT_MEDIA_TRACKS_UPDATED"
+	case CAST_EVENT_MEDIA_POSITION_JUMPED:
+		return "CAST_EVENT_MEDIA_POSITION_JUMPED"
+	case CAST_EVENT_SESSION_STARTED:
+		return "CAST_EVENT_SESSION_STARTED"
+	case CAST_EVENT_SESSION_ENDED:
+		return "CAST_EVENT_SESSION_ENDED"
+	case CAST_EVENT_SESSION_TAKEN_OVER:
+		return "CAST_EVENT_SESSION_TAKEN_OVER"
 	default:
 		return "[?? Invalid GoogleCastEventType value]"
 	}
 }
+
+func (c DeviceCapability) String() string {
+	if c == CAST_CAPABILITY_NONE {
+		return c.FlagString()
+	}
+	str := ""
+	for v := CAST_CAPABILITY_MIN; v <= CAST_CAPABILITY_MAX; v <<= 1 {
+		if c&v == v {
+			str += v.FlagString() + "|"
+		}
+	}
+	return strings.TrimSuffix(str, "|")
+}
+
+func (c DeviceCapability) FlagString() string {
+	switch c {
+	case CAST_CAPABILITY_NONE:
+		return "CAST_CAPABILITY_NONE"
+	case CAST_CAPABILITY_VIDEO_OUT:
+		return "CAST_CAPABILITY_VIDEO_OUT"
+	case CAST_CAPABILITY_VIDEO_IN:
+		return "CAST_CAPABILITY_VIDEO_IN"
+	case CAST_CAPABILITY_AUDIO_OUT:
+		return "CAST_CAPABILITY_AUDIO_OUT"
+	case CAST_CAPABILITY_AUDIO_IN:
+		return "CAST_CAPABILITY_AUDIO_IN"
+	case CAST_CAPABILITY_DEV_MODE:
+		return "CAST_CAPABILITY_DEV_MODE"
+	case CAST_CAPABILITY_MULTIZONE:
+		return "CAST_CAPABILITY_MULTIZONE"
+	default:
+		return "[?? Invalid DeviceCapability value]"
+	}
+}
+
+func (c MediaCommand) String() string {
+	if c == CAST_MEDIA_COMMAND_NONE {
+		return c.FlagString()
+	}
+	str := ""
+	for v := CAST_MEDIA_COMMAND_MIN; v <= CAST_MEDIA_COMMAND_MAX; v <<= 1 {
+		if c&v == v {
+			str += v.FlagString() + "|"
+		}
+	}
+	return strings.TrimSuffix(str, "|")
+}
+
+func (c MediaCommand) FlagString() string {
+	switch c {
+	case CAST_MEDIA_COMMAND_NONE:
+		return "CAST_MEDIA_COMMAND_NONE"
+	case CAST_MEDIA_COMMAND_PAUSE:
+		return "CAST_MEDIA_COMMAND_PAUSE"
+	case CAST_MEDIA_COMMAND_SEEK:
+		return "CAST_MEDIA_COMMAND_SEEK"
+	case CAST_MEDIA_COMMAND_STREAM_VOLUME:
+		return "CAST_MEDIA_COMMAND_STREAM_VOLUME"
+	case CAST_MEDIA_COMMAND_STREAM_MUTE:
+		return "CAST_MEDIA_COMMAND_STREAM_MUTE"
+	case CAST_MEDIA_COMMAND_SKIP_FORWARD:
+		return "CAST_MEDIA_COMMAND_SKIP_FORWARD"
+	case CAST_MEDIA_COMMAND_SKIP_BACKWARD:
+		return "CAST_MEDIA_COMMAND_SKIP_BACKWARD"
+	case CAST_MEDIA_COMMAND_QUEUE_NEXT:
+		return "CAST_MEDIA_COMMAND_QUEUE_NEXT"
+	case CAST_MEDIA_COMMAND_QUEUE_PREV:
+		return "CAST_MEDIA_COMMAND_QUEUE_PREV"
+	default:
+		return "[?? Invalid MediaCommand value]"
+	}
+}
+
+func (s PlayerState) String() string {
+	switch s {
+	case CAST_PLAYER_STATE_NONE:
+		return "CAST_PLAYER_STATE_NONE"
+	case CAST_PLAYER_STATE_IDLE:
+		return "CAST_PLAYER_STATE_IDLE"
+	case CAST_PLAYER_STATE_PLAYING:
+		return "CAST_PLAYER_STATE_PLAYING"
+	case CAST_PLAYER_STATE_PAUSED:
+		return "CAST_PLAYER_STATE_PAUSED"
+	case CAST_PLAYER_STATE_BUFFERING:
+		return "CAST_PLAYER_STATE_BUFFERING"
+	default:
+		return "[?? Invalid PlayerState value]"
+	}
+}
+
+func (p OverflowPolicy) String() string {
+	switch p {
+	case CAST_OVERFLOW_DROP_OLDEST:
+		return "CAST_OVERFLOW_DROP_OLDEST"
+	case CAST_OVERFLOW_DROP_NEWEST:
+		return "CAST_OVERFLOW_DROP_NEWEST"
+	case CAST_OVERFLOW_BLOCK:
+		return "CAST_OVERFLOW_BLOCK"
+	case CAST_OVERFLOW_CLOSE:
+		return "CAST_OVERFLOW_CLOSE"
+	default:
+		return "[?? Invalid OverflowPolicy value]"
+	}
+}
+
+func (m ReplayMode) String() string {
+	switch m {
+	case CAST_REPLAY_NONE:
+		return "CAST_REPLAY_NONE"
+	case CAST_REPLAY_STATE:
+		return "CAST_REPLAY_STATE"
+	case CAST_REPLAY_SEQUENCE:
+		return "CAST_REPLAY_SEQUENCE"
+	default:
+		return "[?? Invalid ReplayMode value]"
+	}
+}
+
+func (t StreamType) String() string {
+	switch t {
+	case CAST_STREAM_TYPE_NONE:
+		return "CAST_STREAM_TYPE_NONE"
+	case CAST_STREAM_TYPE_BUFFERED:
+		return "CAST_STREAM_TYPE_BUFFERED"
+	case CAST_STREAM_TYPE_LIVE:
+		return "CAST_STREAM_TYPE_LIVE"
+	default:
+		return "[?? Invalid StreamType value]"
+	}
+}
+
+func (e *UnsupportedCommandError) Error() string {
+	return fmt.Sprintf("Media command not supported: %v", e.Command)
+}
@@ -0,0 +1,170 @@
+/*
+  Go Language Raspberry Pi Interface
+  (c) Copyright David Thorpe 2019
+  All Rights Reserved
+  Documentation http://djthorpe.github.io/gopi/
+  For Licensing and Usage information, please see LICENSE.md
+*/
+
+package googlecast
+
+import (
+	"fmt"
+	"strconv"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+type MetadataType uint
+
+// MediaMetadata is implemented by GenericMetadata, MovieMetadata,
+// TvShowMetadata, MusicTrackMetadata and PhotoMetadata
+type MediaMetadata interface {
+	Type() MetadataType
+}
+
+// MediaRequest describes media to load into an application
+type MediaRequest struct {
+	ContentId   string
+	ContentType string
+	StreamType  StreamType
+	Duration    float32
+	Metadata    MediaMetadata
+	Autoplay    bool
+	CurrentTime float32
+
+	// Tracks (for example, subtitles created with NewSubtitleTrack),
+	// the identifiers of tracks which are active when loaded and
+	// the appearance of text tracks
+	Tracks         []Track
+	ActiveTrackIds []int
+	TextTrackStyle *TextTrackStyle
+}
+
+type Image struct {
+	URL    string `json:"url"`
+	Width  int    `json:"width,omitempty"`
+	Height int    `json:"height,omitempty"`
+}
+
+type GenericMetadata struct {
+	Title       string  `json:"title,omitempty"`
+	Subtitle    string  `json:"subtitle,omitempty"`
+	Images      []Image `json:"images,omitempty"`
+	ReleaseDate string  `json:"releaseDate,omitempty"`
+}
+
+type MovieMetadata struct {
+	Title       string  `json:"title,omitempty"`
+	Subtitle    string  `json:"subtitle,omitempty"`
+	Studio      string  `json:"studio,omitempty"`
+	Images      []Image `json:"images,omitempty"`
+	ReleaseDate string  `json:"releaseDate,omitempty"`
+}
+
+type TvShowMetadata struct {
+	Title           string  `json:"title,omitempty"`
+	SeriesTitle     string  `json:"seriesTitle,omitempty"`
+	Season          int     `json:"season,omitempty"`
+	Episode         int     `json:"episode,omitempty"`
+	Images          []Image `json:"images,omitempty"`
+	OriginalAirdate string  `json:"originalAirdate,omitempty"`
+}
+
+type MusicTrackMetadata struct {
+	Title       string  `json:"title,omitempty"`
+	AlbumName   string  `json:"albumName,omitempty"`
+	AlbumArtist string  `json:"albumArtist,omitempty"`
+	Artist      string  `json:"artist,omitempty"`
+	Composer    string  `json:"composer,omitempty"`
+	TrackNumber int     `json:"trackNumber,omitempty"`
+	DiscNumber  int     `json:"discNumber,omitempty"`
+	Images      []Image `json:"images,omitempty"`
+	ReleaseDate string  `json:"releaseDate,omitempty"`
+}
+
+type PhotoMetadata struct {
+	Title            string  `json:"title,omitempty"`
+	Artist           string  `json:"artist,omitempty"`
+	Location         string  `json:"location,omitempty"`
+	Latitude         float64 `json:"latitude,omitempty"`
+	Longitude        float64 `json:"longitude,omitempty"`
+	Width            int     `json:"width,omitempty"`
+	Height           int     `json:"height,omitempty"`
+	CreationDateTime string  `json:"creationDateTime,omitempty"`
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// CONSTANTS
+
+const (
+	CAST_METADATA_TYPE_GENERIC MetadataType = iota
+	CAST_METADATA_TYPE_MOVIE
+	CAST_METADATA_TYPE_TV_SHOW
+	CAST_METADATA_TYPE_MUSIC_TRACK
+	CAST_METADATA_TYPE_PHOTO
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// IMPLEMENTATION
+
+func (GenericMetadata) Type() MetadataType {
+	return CAST_METADATA_TYPE_GENERIC
+}
+
+func (MovieMetadata) Type() MetadataType {
+	return CAST_METADATA_TYPE_MOVIE
+}
+
+func (TvShowMetadata) Type() MetadataType {
+	return CAST_METADATA_TYPE_TV_SHOW
+}
+
+func (MusicTrackMetadata) Type() MetadataType {
+	return CAST_METADATA_TYPE_MUSIC_TRACK
+}
+
+func (PhotoMetadata) Type() MetadataType {
+	return CAST_METADATA_TYPE_PHOTO
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+func (t MetadataType) String() string {
+	switch t {
+	case CAST_METADATA_TYPE_GENERIC:
+		return "CAST_METADATA_TYPE_GENERIC"
+	case CAST_METADATA_TYPE_MOVIE:
+		return "CAST_METADATA_TYPE_MOVIE"
+	case CAST_METADATA_TYPE_TV_SHOW:
+		return "CAST_METADATA_TYPE_TV_SHOW"
+	case CAST_METADATA_TYPE_MUSIC_TRACK:
+		return "CAST_METADATA_TYPE_MUSIC_TRACK"
+	case CAST_METADATA_TYPE_PHOTO:
+		return "CAST_METADATA_TYPE_PHOTO"
+	default:
+		return "[?? Invalid MetadataType value]"
+	}
+}
+
+func (this GenericMetadata) String() string {
+	return fmt.Sprintf("<googlecast.GenericMetadata>{ title=%v subtitle=%v }", strconv.Quote(this.Title), strconv.Quote(this.Subtitle))
+}
+
+func (this MovieMetadata) String() string {
+	return fmt.Sprintf("<googlecast.MovieMetadata>{ title=%v studio=%v }", strconv.Quote(this.Title), strconv.Quote(this.Studio))
+}
+
+func (this TvShowMetadata) String() string {
+	return fmt.Sprintf("<googlecast.TvShowMetadata>{ title=%v series_title=%v season=%v episode=%v }", strconv.Quote(this.Title), strconv.Quote(this.SeriesTitle), this.Season, this.Episode)
+}
+
+func (this MusicTrackMetadata) String() string {
+	return fmt.Sprintf("<googlecast.MusicTrackMetadata>{ title=%v artist=%v album=%v track=%v }", strconv.Quote(this.Title), strconv.Quote(this.Artist), strconv.Quote(this.AlbumName), this.TrackNumber)
+}
+
+func (this PhotoMetadata) String() string {
+	return fmt.Sprintf("<googlecast.PhotoMetadata>{ title=%v location=%v lat=%v lng=%v }", strconv.Quote(this.Title), strconv.Quote(this.Location), this.Latitude, this.Longitude)
+}
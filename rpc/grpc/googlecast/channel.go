@@ -0,0 +1,131 @@
+/*
+	Go Language Raspberry Pi Interface
+	(c) Copyright David Thorpe 2019
+	All Rights Reserved
+	Documentation http://djthorpe.github.io/gopi/
+	For Licensing and Usage information, please see LICENSE.md
+*/
+
+package googlecast
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	// Frameworks
+	googlecast "github.com/djthorpe/googlecast"
+	gopi "github.com/djthorpe/gopi"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// castchannel is a channel on the remote service. Properties are
+// those of the event which the channel was returned from, and controls
+// are carried out through the client, returning a zero request id.
+// Controls which the service does not provide return
+// gopi.ErrNotImplemented
+type castchannel struct {
+	*caststate
+	client     *Client
+	id         string
+	remoteAddr string
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PROPERTIES
+
+func (this *castchannel) RemoteAddr() string {
+	return this.remoteAddr
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// CONTROLS
+
+func (this *castchannel) AppAvailability(context.Context, ...string) (map[string]bool, error) {
+	return nil, gopi.ErrNotImplemented
+}
+
+func (this *castchannel) LoadMedia(request googlecast.MediaRequest) (int, error) {
+	return 0, this.client.LoadMedia(this.id, request)
+}
+
+func (this *castchannel) SetPlay(state bool) (int, error) {
+	if state {
+		return 0, this.client.Play(this.id)
+	} else {
+		return 0, this.client.Stop(this.id)
+	}
+}
+
+func (this *castchannel) SetPause(state bool) (int, error) {
+	if state {
+		return 0, this.client.Pause(this.id)
+	} else {
+		return 0, this.client.Play(this.id)
+	}
+}
+
+func (this *castchannel) SetSeek(value float32) (int, error) {
+	return 0, this.client.Seek(this.id, value)
+}
+
+func (this *castchannel) SetSkip(float32) (int, error) {
+	return 0, gopi.ErrNotImplemented
+}
+
+func (this *castchannel) SetStreamVolume(float32) (int, error) {
+	return 0, gopi.ErrNotImplemented
+}
+
+func (this *castchannel) SetStreamMuted(bool) (int, error) {
+	return 0, gopi.ErrNotImplemented
+}
+
+func (this *castchannel) SetTrackNext() (int, error) {
+	return 0, gopi.ErrNotImplemented
+}
+
+func (this *castchannel) SetTrackPrev() (int, error) {
+	return 0, gopi.ErrNotImplemented
+}
+
+func (this *castchannel) SetSeekToLive() (int, error) {
+	return 0, gopi.ErrNotImplemented
+}
+
+func (this *castchannel) SetPlaybackRate(float32) (int, error) {
+	return 0, gopi.ErrNotImplemented
+}
+
+func (this *castchannel) SetActiveTracks(...int) (int, error) {
+	return 0, gopi.ErrNotImplemented
+}
+
+func (this *castchannel) SetTextTrackStyle(googlecast.TextTrackStyle) (int, error) {
+	return 0, gopi.ErrNotImplemented
+}
+
+func (this *castchannel) LaunchApp(appId string) (int, error) {
+	return 0, this.client.LaunchApp(this.id, appId)
+}
+
+func (this *castchannel) StopApp() (int, error) {
+	return 0, this.client.StopApp(this.id)
+}
+
+func (this *castchannel) SetVolume(value float32) (int, error) {
+	return 0, this.client.SetVolume(this.id, value)
+}
+
+func (this *castchannel) SetMuted(value bool) (int, error) {
+	return 0, this.client.SetMuted(this.id, value)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+func (this *castchannel) String() string {
+	return fmt.Sprintf("<googlecast.Channel>{ id=%v remote_addr=%v state=%v }", strconv.Quote(this.id), strconv.Quote(this.remoteAddr), this.caststate)
+}
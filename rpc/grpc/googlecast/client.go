@@ -12,6 +12,7 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"sync/atomic"
 	"time"
 
 	// Frameworks
@@ -29,6 +30,11 @@ import (
 // TYPES
 
 type Client struct {
+	// Sequence number and epoch of the last event received, which
+	// are first for 64-bit alignment
+	sequence uint64
+	epoch    uint64
+
 	pb.GoogleCastClient
 	gopi.RPCClientConn
 	event.Publisher
@@ -97,17 +103,212 @@ func (this *Client) Devices() ([]googlecast.Device, error) {
 	}
 }
 
-func (this *Client) StreamEvents(ctx context.Context) error {
+func (this *Client) Reboot(id string) error {
+	this.RPCClientConn.Lock()
+	defer this.RPCClientConn.Unlock()
+
+	if _, err := this.GoogleCastClient.Reboot(this.NewContext(0), &pb.DeviceRequest{Id: id}); err != nil {
+		return err
+	} else {
+		return nil
+	}
+}
+
+func (this *Client) FactoryReset(id, confirm string) error {
+	this.RPCClientConn.Lock()
+	defer this.RPCClientConn.Unlock()
+
+	if _, err := this.GoogleCastClient.FactoryReset(this.NewContext(0), &pb.FactoryResetRequest{Id: id, Confirm: confirm}); err != nil {
+		return err
+	} else {
+		return nil
+	}
+}
+
+func (this *Client) SetName(id, name string) error {
+	this.RPCClientConn.Lock()
+	defer this.RPCClientConn.Unlock()
+
+	if _, err := this.GoogleCastClient.SetName(this.NewContext(0), &pb.SetNameRequest{Id: id, Name: name}); err != nil {
+		return err
+	} else {
+		return nil
+	}
+}
+
+func (this *Client) SetTimezone(id, timezone string) error {
+	this.RPCClientConn.Lock()
+	defer this.RPCClientConn.Unlock()
+
+	if _, err := this.GoogleCastClient.SetTimezone(this.NewContext(0), &pb.SetTimezoneRequest{Id: id, Timezone: timezone}); err != nil {
+		return err
+	} else {
+		return nil
+	}
+}
+
+func (this *Client) SetLocale(id, locale string) error {
+	this.RPCClientConn.Lock()
+	defer this.RPCClientConn.Unlock()
+
+	if _, err := this.GoogleCastClient.SetLocale(this.NewContext(0), &pb.SetLocaleRequest{Id: id, Locale: locale}); err != nil {
+		return err
+	} else {
+		return nil
+	}
+}
+
+func (this *Client) Connect(id string) error {
+	this.RPCClientConn.Lock()
+	defer this.RPCClientConn.Unlock()
+
+	if _, err := this.GoogleCastClient.Connect(this.NewContext(0), &pb.DeviceRequest{Id: id}); err != nil {
+		return err
+	} else {
+		return nil
+	}
+}
+
+func (this *Client) Disconnect(id string) error {
+	this.RPCClientConn.Lock()
+	defer this.RPCClientConn.Unlock()
+
+	if _, err := this.GoogleCastClient.Disconnect(this.NewContext(0), &pb.DeviceRequest{Id: id}); err != nil {
+		return err
+	} else {
+		return nil
+	}
+}
+
+func (this *Client) Status(id string) (googlecast.State, error) {
+	this.RPCClientConn.Lock()
+	defer this.RPCClientConn.Unlock()
+
+	if state, err := this.GoogleCastClient.Status(this.NewContext(0), &pb.DeviceRequest{Id: id}); err != nil {
+		return nil, err
+	} else {
+		return fromProtoState(state), nil
+	}
+}
+
+func (this *Client) Play(id string) error {
+	this.RPCClientConn.Lock()
+	defer this.RPCClientConn.Unlock()
+
+	if _, err := this.GoogleCastClient.Play(this.NewContext(0), &pb.DeviceRequest{Id: id}); err != nil {
+		return err
+	} else {
+		return nil
+	}
+}
+
+func (this *Client) Pause(id string) error {
+	this.RPCClientConn.Lock()
+	defer this.RPCClientConn.Unlock()
+
+	if _, err := this.GoogleCastClient.Pause(this.NewContext(0), &pb.DeviceRequest{Id: id}); err != nil {
+		return err
+	} else {
+		return nil
+	}
+}
+
+func (this *Client) Stop(id string) error {
+	this.RPCClientConn.Lock()
+	defer this.RPCClientConn.Unlock()
+
+	if _, err := this.GoogleCastClient.Stop(this.NewContext(0), &pb.DeviceRequest{Id: id}); err != nil {
+		return err
+	} else {
+		return nil
+	}
+}
+
+func (this *Client) Seek(id string, position float32) error {
+	this.RPCClientConn.Lock()
+	defer this.RPCClientConn.Unlock()
+
+	if _, err := this.GoogleCastClient.Seek(this.NewContext(0), &pb.SeekRequest{Id: id, Position: position}); err != nil {
+		return err
+	} else {
+		return nil
+	}
+}
+
+func (this *Client) SetVolume(id string, level float32) error {
+	this.RPCClientConn.Lock()
+	defer this.RPCClientConn.Unlock()
+
+	if _, err := this.GoogleCastClient.SetVolume(this.NewContext(0), &pb.SetVolumeRequest{Id: id, Level: level}); err != nil {
+		return err
+	} else {
+		return nil
+	}
+}
+
+func (this *Client) SetMuted(id string, muted bool) error {
 	this.RPCClientConn.Lock()
 	defer this.RPCClientConn.Unlock()
 
-	// Errors channel receives errors from recv
-	now := time.Now()
+	if _, err := this.GoogleCastClient.SetMuted(this.NewContext(0), &pb.SetMutedRequest{Id: id, Muted: muted}); err != nil {
+		return err
+	} else {
+		return nil
+	}
+}
+
+func (this *Client) LaunchApp(id, appId string) error {
+	this.RPCClientConn.Lock()
+	defer this.RPCClientConn.Unlock()
+
+	if _, err := this.GoogleCastClient.LaunchApp(this.NewContext(0), &pb.LaunchAppRequest{Id: id, AppId: appId}); err != nil {
+		return err
+	} else {
+		return nil
+	}
+}
+
+func (this *Client) StopApp(id string) error {
+	this.RPCClientConn.Lock()
+	defer this.RPCClientConn.Unlock()
+
+	if _, err := this.GoogleCastClient.StopApp(this.NewContext(0), &pb.DeviceRequest{Id: id}); err != nil {
+		return err
+	} else {
+		return nil
+	}
+}
+
+func (this *Client) LoadMedia(id string, request googlecast.MediaRequest) error {
+	this.RPCClientConn.Lock()
+	defer this.RPCClientConn.Unlock()
+
+	if _, err := this.GoogleCastClient.LoadMedia(this.NewContext(0), toProtoLoadMediaRequest(id, request)); err != nil {
+		return err
+	} else {
+		return nil
+	}
+}
+
+// StreamEvents emits events which match the device identifiers, names
+// and types in the options, until the context is cancelled. When called
+// again, events missed since the last event received are emitted (or the
+// current state if the service no longer retains them or has restarted),
+// so it should be called again when a ResourceExhausted error is returned
+// because events were not received quickly enough
+func (this *Client) StreamEvents(ctx context.Context, options googlecast.SubscribeOptions) error {
+	// Time a message was last received, and errors channel which
+	// receives errors from recv and is closed when the stream ends
+	received := time.Now().UnixNano()
 	ctx_, cancel := context.WithCancel(ctx)
+	defer cancel()
 	errors := make(chan error)
 
-	// Open stream
-	stream, err := this.GoogleCastClient.StreamEvents(ctx_, &empty.Empty{})
+	// Open stream, holding the lock only while opening so that events
+	// can be acted on through other calls while streaming
+	this.RPCClientConn.Lock()
+	stream, err := this.GoogleCastClient.StreamEvents(ctx_, toProtoStreamEventsRequest(options, atomic.LoadUint64(&this.epoch), atomic.LoadUint64(&this.sequence)))
+	this.RPCClientConn.Unlock()
 	if err != nil {
 		return err
 	}
@@ -121,26 +322,30 @@ func (this *Client) StreamEvents(ctx context.Context) error {
 			} else if err != nil {
 				errors <- err
 				break FOR_LOOP
-			} else if evt := fromProtoEvent(evt_, this.RPCClientConn); evt != nil {
-				now = time.Now()
+			} else if evt_.Keepalive {
+				this.received(evt_)
+				atomic.StoreInt64(&received, time.Now().UnixNano())
+			} else if evt := fromProtoEvent(evt_, this); evt != nil {
+				this.received(evt_)
+				atomic.StoreInt64(&received, time.Now().UnixNano())
 				this.Emit(evt)
 			}
 		}
 		close(errors)
 	}()
 
-	// Continue until error or io.EOF is returned, or nothing received after timeout
+	// Continue until error or io.EOF is returned, or cancel when nothing
+	// is received after timeout
 	heartbeat := time.NewTicker(HEARTBEAT_TIMEOUT)
 	defer heartbeat.Stop()
 	for {
 		select {
 		case <-heartbeat.C:
-			if time.Since(now) > HEARTBEAT_TIMEOUT {
-				fmt.Println("TIMEOUT!")
+			if time.Since(time.Unix(0, atomic.LoadInt64(&received))) > HEARTBEAT_TIMEOUT {
 				cancel()
 			}
-		case <-ctx_.Done():
-			if err := <-errors; err == nil || grpc.IsErrCanceled(err) {
+		case err := <-errors:
+			if err == nil || grpc.IsErrCanceled(err) {
 				return nil
 			} else {
 				return err
@@ -149,6 +354,21 @@ func (this *Client) StreamEvents(ctx context.Context) error {
 	}
 }
 
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// received records the epoch and sequence number of an event. When the
+// epoch changes the service has restarted, and the sequence number is
+// replaced rather than only increased
+func (this *Client) received(evt *pb.CastEvent) {
+	if epoch := evt.GetEpoch(); epoch != atomic.LoadUint64(&this.epoch) {
+		atomic.StoreUint64(&this.epoch, epoch)
+		atomic.StoreUint64(&this.sequence, evt.GetSequence())
+	} else if sequence := evt.GetSequence(); sequence > atomic.LoadUint64(&this.sequence) {
+		atomic.StoreUint64(&this.sequence, sequence)
+	}
+}
+
 ////////////////////////////////////////////////////////////////////////////////
 // STRINGIFY
 
@@ -0,0 +1,35 @@
+package googlecast
+
+import (
+	"testing"
+
+	// Protocol buffers
+	pb "github.com/djthorpe/googlecast/rpc/protobuf/googlecast"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// RESUME
+
+func TestClientReceived_000(t *testing.T) {
+	// The sequence number increases within an epoch, and is replaced
+	// when the epoch changes
+	this := &Client{}
+	for _, test := range []struct {
+		evt      *pb.CastEvent
+		epoch    uint64
+		sequence uint64
+	}{
+		{&pb.CastEvent{Epoch: 1, Sequence: 10}, 1, 10},
+		{&pb.CastEvent{Epoch: 1, Sequence: 12}, 1, 12},
+		{&pb.CastEvent{Epoch: 1, Sequence: 11}, 1, 12},
+		{&pb.CastEvent{Epoch: 1, Keepalive: true}, 1, 12},
+		{&pb.CastEvent{Epoch: 2, Sequence: 3}, 2, 3},
+		{&pb.CastEvent{Epoch: 2, Sequence: 4}, 2, 4},
+		{&pb.CastEvent{Epoch: 3, Keepalive: true}, 3, 0},
+	} {
+		this.received(test.evt)
+		if this.epoch != test.epoch || this.sequence != test.sequence {
+			t.Errorf("Unexpected epoch %v and sequence %v after %v", this.epoch, this.sequence, test.evt)
+		}
+	}
+}
@@ -10,14 +10,20 @@ package googlecast
 
 import (
 	// Frameworks
+	"context"
 	"fmt"
 	"strconv"
+	"time"
 
 	googlecast "github.com/djthorpe/googlecast"
 	"github.com/djthorpe/gopi"
 
 	// Protocol buffers
 	pb "github.com/djthorpe/googlecast/rpc/protobuf/googlecast"
+	ptypes "github.com/golang/protobuf/ptypes"
+	timestamp "github.com/golang/protobuf/ptypes/timestamp"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
 )
 
 ////////////////////////////////////////////////////////////////////////////////
@@ -29,7 +35,8 @@ type castdevice struct {
 
 type castevent struct {
 	*pb.CastEvent
-	gopi.RPCClientConn
+	client   *Client
+	received time.Time
 }
 
 ////////////////////////////////////////////////////////////////////////////////
@@ -74,16 +81,64 @@ func (this *castdevice) State() uint {
 	}
 }
 
+func (this *castdevice) Capabilities() googlecast.DeviceCapability {
+	if this.CastDevice == nil {
+		return googlecast.CAST_CAPABILITY_NONE
+	} else {
+		return googlecast.DeviceCapability(this.GetCapabilities())
+	}
+}
+
+func (this *castdevice) FirstSeen() time.Time {
+	if this.CastDevice == nil {
+		return time.Time{}
+	} else {
+		return fromProtoTimestamp(this.GetFirstSeen())
+	}
+}
+
+func (this *castdevice) LastSeen() time.Time {
+	if this.CastDevice == nil {
+		return time.Time{}
+	} else {
+		return fromProtoTimestamp(this.GetLastSeen())
+	}
+}
+
+func (this *castdevice) Reachable() bool {
+	if this.CastDevice == nil {
+		return false
+	} else {
+		return this.GetReachable()
+	}
+}
+
+func (this *castdevice) Verified() bool {
+	if this.CastDevice == nil {
+		return false
+	} else {
+		return this.GetVerified()
+	}
+}
+
+func (this *castdevice) Info(context.Context) (googlecast.DeviceInfo, error) {
+	return nil, gopi.ErrNotImplemented
+}
+
 func (this *castdevice) String() string {
 	if this == nil {
 		return "<googlecast.Device>{ nil }"
 	} else {
-		return fmt.Sprintf("<googlecast.Device>{ id=%v name=%v model=%v service=%v state=%v }",
+		return fmt.Sprintf("<googlecast.Device>{ id=%v name=%v model=%v service=%v state=%v capabilities=%v last_seen=%v reachable=%v verified=%v }",
 			strconv.Quote(this.Id()),
 			strconv.Quote(this.Name()),
 			strconv.Quote(this.Model()),
 			strconv.Quote(this.Service()),
 			this.State(),
+			this.Capabilities(),
+			this.LastSeen().Format(time.RFC3339),
+			this.Reachable(),
+			this.Verified(),
 		)
 	}
 }
@@ -100,7 +155,7 @@ func (this *castevent) Type() googlecast.EventType {
 }
 
 func (this *castevent) Source() gopi.Driver {
-	return this.RPCClientConn
+	return this.client.RPCClientConn
 }
 
 func (this *castevent) Name() string {
@@ -108,11 +163,79 @@ func (this *castevent) Name() string {
 }
 
 func (this *castevent) Channel() googlecast.Channel {
-	return nil
+	if this.GetRemoteAddr() == "" || this.GetDevice() == nil {
+		return nil
+	} else {
+		return &castchannel{this.state(), this.client, this.GetDevice().GetId(), this.GetRemoteAddr()}
+	}
 }
 
 func (this *castevent) Device() googlecast.Device {
-	return nil
+	if this.GetDevice() == nil {
+		return nil
+	} else {
+		return &castdevice{this.GetDevice()}
+	}
+}
+
+func (this *castevent) Sequence() uint64 {
+	return this.GetSequence()
+}
+
+func (this *castevent) Timestamp() time.Time {
+	return fromProtoTimestamp(this.GetTs())
+}
+
+// State returns the state carried by the event, which includes the
+// volume, application or media for the event type
+func (this *castevent) State() googlecast.State {
+	if this.GetRemoteAddr() == "" {
+		return nil
+	} else {
+		return this.state()
+	}
+}
+
+// Previous returns the channel state before the change, or nil
+// for device events
+func (this *castevent) Previous() googlecast.State {
+	if prev := this.GetPrevious(); prev == nil {
+		return nil
+	} else {
+		return &caststate{prev, this.received}
+	}
+}
+
+// Err returns the error when the remote service could not act
+// on an event, or nil otherwise
+func (this *castevent) Err() error {
+	if err := this.GetError(); err == nil {
+		return nil
+	} else {
+		return status.Error(codes.Code(err.GetCode()), err.GetMessage())
+	}
+}
+
+func (this *castevent) state() *caststate {
+	return &caststate{&pb.CastState{
+		Application: this.GetApplication(),
+		Volume:      this.GetVolume(),
+		Media:       this.GetMedia(),
+		ActiveInput: this.GetActiveInput(),
+		Standby:     this.GetStandby(),
+	}, this.received}
+}
+
+func (this *castevent) String() string {
+	if err := this.Err(); err != nil {
+		return fmt.Sprintf("<%s>{ %v seq=%v device=%v err=%v }", this.Name(), this.Type(), this.Sequence(), this.Device(), err)
+	} else if channel := this.Channel(); channel != nil {
+		return fmt.Sprintf("<%s>{ %v seq=%v channel=%v device=%v }", this.Name(), this.Type(), this.Sequence(), channel, this.Device())
+	} else if device := this.Device(); device != nil {
+		return fmt.Sprintf("<%s>{ %v seq=%v device=%v }", this.Name(), this.Type(), this.Sequence(), device)
+	} else {
+		return fmt.Sprintf("<%s>{ %v seq=%v }", this.Name(), this.Type(), this.Sequence())
+	}
 }
 
 ////////////////////////////////////////////////////////////////////////////////
@@ -129,12 +252,44 @@ func fromProtoDevicesReply(pb *pb.DevicesReply) []googlecast.Device {
 	return devices
 }
 
-func fromProtoEvent(pb *pb.CastEvent, conn gopi.RPCClientConn) googlecast.Event {
+func fromProtoTimestamp(ts *timestamp.Timestamp) time.Time {
+	if ts == nil {
+		return time.Time{}
+	} else if ts_, err := ptypes.Timestamp(ts); err != nil {
+		return time.Time{}
+	} else {
+		return ts_
+	}
+}
+
+func fromProtoEvent(pb *pb.CastEvent, client *Client) googlecast.Event {
 	if pb == nil {
 		return nil
 	} else {
-		return &castevent{pb, conn}
+		return &castevent{pb, client, time.Now()}
+	}
+}
+
+// fromProtoStreamEventsRequest returns subscription options, which
+// replay missed events when a sequence number is provided for the
+// epoch of the service, or the current state otherwise. The subscription
+// is closed when the client does not keep up, so events are not dropped
+// without the client knowing
+func fromProtoStreamEventsRequest(pb *pb.StreamEventsRequest, epoch uint64) googlecast.SubscribeOptions {
+	options := googlecast.SubscribeOptions{
+		DeviceIds: pb.GetDeviceIds(),
+		Names:     pb.GetNames(),
+		Overflow:  googlecast.CAST_OVERFLOW_CLOSE,
+		Replay:    googlecast.CAST_REPLAY_STATE,
+	}
+	for _, type_ := range pb.GetTypes() {
+		options.Types = append(options.Types, googlecast.EventType(type_))
 	}
+	if pb.GetSequence() > 0 && pb.GetEpoch() == epoch {
+		options.Replay = googlecast.CAST_REPLAY_SEQUENCE
+		options.Sequence = pb.GetSequence()
+	}
+	return options
 }
 
 ////////////////////////////////////////////////////////////////////////////////
@@ -158,21 +313,89 @@ func toProtoDevice(device googlecast.Device) *pb.CastDevice {
 		return nil
 	} else {
 		return &pb.CastDevice{
-			Id:      device.Id(),
-			Name:    device.Name(),
-			Model:   device.Model(),
-			Service: device.Service(),
-			State:   uint32(device.State()),
+			Id:           device.Id(),
+			Name:         device.Name(),
+			Model:        device.Model(),
+			Service:      device.Service(),
+			State:        uint32(device.State()),
+			Capabilities: uint32(device.Capabilities()),
+			FirstSeen:    toProtoTimestamp(device.FirstSeen()),
+			LastSeen:     toProtoTimestamp(device.LastSeen()),
+			Reachable:    device.Reachable(),
+			Verified:     device.Verified(),
 		}
 	}
 }
 
+func toProtoTimestamp(ts time.Time) *timestamp.Timestamp {
+	if ts.IsZero() {
+		return nil
+	} else if ts_, err := ptypes.TimestampProto(ts); err != nil {
+		return nil
+	} else {
+		return ts_
+	}
+}
+
+func toProtoStreamEventsRequest(options googlecast.SubscribeOptions, epoch, sequence uint64) *pb.StreamEventsRequest {
+	req := &pb.StreamEventsRequest{
+		DeviceIds: options.DeviceIds,
+		Names:     options.Names,
+		Sequence:  sequence,
+		Epoch:     epoch,
+	}
+	for _, type_ := range options.Types {
+		req.Types = append(req.Types, pb.CastEvent_EventType(type_))
+	}
+	return req
+}
+
 func toProtoEvent(evt googlecast.Event) *pb.CastEvent {
 	if evt == nil {
 		return nil
 	}
-	return &pb.CastEvent{
-		Type:   pb.CastEvent_EventType(evt.Type()),
-		Device: toProtoDevice(evt.Device()),
+	reply := &pb.CastEvent{
+		Type:     pb.CastEvent_EventType(evt.Type()),
+		Device:   toProtoDevice(evt.Device()),
+		Sequence: evt.Sequence(),
+		Ts:       toProtoTimestamp(evt.Timestamp()),
+	}
+	if channel := evt.Channel(); channel != nil {
+		reply.RemoteAddr = channel.RemoteAddr()
+	}
+	if state := evt.State(); state != nil {
+		reply.ActiveInput = state.ActiveInput()
+		reply.Standby = state.StandBy()
+		switch evt.Type() {
+		case googlecast.CAST_EVENT_VOLUME_UPDATED:
+			if volume := toProtoVolume(state.Volume()); volume != nil {
+				reply.Payload = &pb.CastEvent_Volume{Volume: volume}
+			}
+		case googlecast.CAST_EVENT_CHANNEL_CONNECT, googlecast.CAST_EVENT_APPLICATION_UPDATED, googlecast.CAST_EVENT_SESSION_STARTED, googlecast.CAST_EVENT_SESSION_ENDED, googlecast.CAST_EVENT_SESSION_TAKEN_OVER:
+			if application := toProtoApplication(state.Application()); application != nil {
+				reply.Payload = &pb.CastEvent_Application{Application: application}
+			}
+		case googlecast.CAST_EVENT_MEDIA_UPDATED, googlecast.CAST_EVENT_MEDIA_PLAYER_STATE_UPDATED, googlecast.CAST_EVENT_MEDIA_CONTENT_UPDATED, googlecast.CAST_EVENT_MEDIA_QUEUE_UPDATED, googlecast.CAST_EVENT_MEDIA_TRACKS_UPDATED, googlecast.CAST_EVENT_MEDIA_POSITION_JUMPED:
+			if media := toProtoMedia(state.Media()); media != nil {
+				reply.Payload = &pb.CastEvent_Media{Media: media}
+			}
+		}
+	}
+	if prev := evt.Previous(); prev != nil {
+		reply.Previous = toProtoState(prev)
+	}
+	if evt_, ok := evt.(googlecast.ErrorEvent); ok && evt_.Err() != nil {
+		reply.Payload = &pb.CastEvent_Error{Error: toProtoError(evt_.Err())}
+	}
+	return reply
+}
+
+func toProtoError(err error) *pb.CastError {
+	if err == nil {
+		return nil
+	} else if status_, ok := status.FromError(toStatusError(err)); ok == false {
+		return &pb.CastError{Code: uint32(codes.Unknown), Message: err.Error()}
+	} else {
+		return &pb.CastError{Code: uint32(status_.Code()), Message: status_.Message()}
 	}
 }
@@ -0,0 +1,50 @@
+package googlecast
+
+import (
+	"testing"
+
+	// Frameworks
+	googlecast "github.com/djthorpe/googlecast"
+
+	// Protocol buffers
+	pb "github.com/djthorpe/googlecast/rpc/protobuf/googlecast"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// STREAM EVENTS REQUEST
+
+func TestStreamEventsRequest_000(t *testing.T) {
+	// Missed events are replayed only for the epoch of the service
+	for _, test := range []struct {
+		req    *pb.StreamEventsRequest
+		replay googlecast.ReplayMode
+	}{
+		{&pb.StreamEventsRequest{}, googlecast.CAST_REPLAY_STATE},
+		{&pb.StreamEventsRequest{Epoch: 1}, googlecast.CAST_REPLAY_STATE},
+		{&pb.StreamEventsRequest{Epoch: 1, Sequence: 10}, googlecast.CAST_REPLAY_SEQUENCE},
+		{&pb.StreamEventsRequest{Epoch: 2, Sequence: 10}, googlecast.CAST_REPLAY_STATE},
+		{&pb.StreamEventsRequest{Sequence: 10}, googlecast.CAST_REPLAY_STATE},
+	} {
+		if options := fromProtoStreamEventsRequest(test.req, 1); options.Replay != test.replay {
+			t.Errorf("Unexpected replay %v for %v", options.Replay, test.req)
+		} else if options.Replay == googlecast.CAST_REPLAY_SEQUENCE && options.Sequence != test.req.Sequence {
+			t.Errorf("Unexpected sequence %v for %v", options.Sequence, test.req)
+		}
+	}
+}
+
+func TestStreamEventsRequest_001(t *testing.T) {
+	// Request round trip
+	options := googlecast.SubscribeOptions{
+		DeviceIds: []string{"a1*"},
+		Names:     []string{"Kitchen*"},
+		Types:     []googlecast.EventType{googlecast.CAST_EVENT_MEDIA_UPDATED},
+	}
+	if options_ := fromProtoStreamEventsRequest(toProtoStreamEventsRequest(options, 1, 10), 1); options_.Sequence != 10 {
+		t.Error("Unexpected sequence", options_.Sequence)
+	} else if len(options_.DeviceIds) != 1 || options_.DeviceIds[0] != "a1*" || len(options_.Names) != 1 || options_.Names[0] != "Kitchen*" {
+		t.Error("Unexpected filter", options_)
+	} else if len(options_.Types) != 1 || options_.Types[0] != googlecast.CAST_EVENT_MEDIA_UPDATED {
+		t.Error("Unexpected types", options_.Types)
+	}
+}
@@ -10,8 +10,13 @@ package googlecast
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"net"
+	"strconv"
 	"sync"
+	"syscall"
 	"time"
 
 	// Frameworks
@@ -23,6 +28,8 @@ import (
 	// Protocol buffers
 	pb "github.com/djthorpe/googlecast/rpc/protobuf/googlecast"
 	empty "github.com/golang/protobuf/ptypes/empty"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
 )
 
 ////////////////////////////////////////////////////////////////////////////////
@@ -37,12 +44,20 @@ type service struct {
 	log     gopi.Logger
 	cast    googlecast.Cast
 	channel map[string]googlecast.Channel
+	epoch   uint64
 
 	event.Tasks
 	event.Publisher
 	sync.Mutex
 }
 
+// errorevent is sent to streaming clients when the service could not
+// act on an event
+type errorevent struct {
+	googlecast.Event
+	err error
+}
+
 ////////////////////////////////////////////////////////////////////////////////
 // OPEN AND CLOSE
 
@@ -58,6 +73,7 @@ func (config Service) Open(log gopi.Logger) (gopi.Driver, error) {
 	this.log = log
 	this.cast = config.Cast
 	this.channel = make(map[string]googlecast.Channel)
+	this.epoch = uint64(time.Now().UnixNano())
 
 	// Register service with GRPC server
 	pb.RegisterGoogleCastServer(config.Server.(grpc.GRPCServer).GRPCServer(), this)
@@ -119,11 +135,15 @@ func (this *service) Devices(context.Context, *empty.Empty) (*pb.DevicesReply, e
 	return toProtoDevicesReply(this.cast.Devices()), nil
 }
 
-// Stream events
-func (this *service) StreamEvents(_ *empty.Empty, stream pb.GoogleCast_StreamEventsServer) error {
-	this.log.Debug2("<grpc.service.googlecast.StreamEvents>{}")
+// Stream events which match the request filter, resuming from the
+// sequence number in the request or starting with the current state.
+// The stream ends with a ResourceExhausted error when the client does
+// not keep up, and the client should resume from the last event received
+func (this *service) StreamEvents(req *pb.StreamEventsRequest, stream pb.GoogleCast_StreamEventsServer) error {
+	this.log.Debug2("<grpc.service.googlecast.StreamEvents>{ req=%v }", req)
 
-	events := this.cast.Subscribe()
+	var result error
+	events := this.cast.SubscribeEvents(fromProtoStreamEventsRequest(req, this.epoch))
 	cancel := this.Subscribe()
 	ticker := time.NewTicker(time.Second)
 FOR_LOOP:
@@ -132,54 +152,278 @@ FOR_LOOP:
 		case evt := <-events:
 			if evt == nil {
 				break FOR_LOOP
-			} else if evt_, ok := evt.(googlecast.Event); ok {
-				fmt.Println(evt_)
-				if err := stream.Send(toProtoEvent(evt_)); err != nil {
+			} else if evt_, ok := evt.(googlecast.ErrorEvent); ok && errors.Is(evt_.Err(), googlecast.ErrOverflow) {
+				result = status.Error(codes.ResourceExhausted, evt_.Err().Error())
+				break FOR_LOOP
+			}
+			this.log.Debug2("StreamEvents: %v", evt)
+			if err := this.send(stream, toProtoEvent(evt)); err != nil {
+				this.log.Warn("StreamEvents: %v", err)
+				break FOR_LOOP
+			}
+		case <-ticker.C:
+			if err := this.send(stream, &pb.CastEvent{Keepalive: true}); err != nil {
+				this.log.Warn("StreamEvents: %v", err)
+				break FOR_LOOP
+			}
+		case evt := <-cancel:
+			// Send errors from the service, or end the stream
+			if evt_, ok := evt.(*errorevent); ok {
+				if err := this.send(stream, toProtoEvent(evt_)); err != nil {
 					this.log.Warn("StreamEvents: %v", err)
 					break FOR_LOOP
 				}
 			} else {
-				this.log.Warn("StreamEvents: Ignoring event: %v", evt)
-			}
-		case <-ticker.C:
-			if err := stream.Send(&pb.CastEvent{}); err != nil {
-				this.log.Warn("StreamEvents: %v", err)
 				break FOR_LOOP
 			}
-		case <-cancel:
-			break FOR_LOOP
 		}
 	}
 
 	// Stop ticker, unsubscribe from events
 	ticker.Stop()
-	this.cast.Unsubscribe(events)
+	this.cast.UnsubscribeEvents(events)
 	this.Unsubscribe(cancel)
 
 	this.log.Debug2("StreamEvents: Ended")
 
-	// Return success
-	return nil
+	// Return any error
+	return result
+}
+
+func (this *service) Reboot(ctx context.Context, req *pb.DeviceRequest) (*empty.Empty, error) {
+	this.log.Debug("<grpc.service.googlecast.Reboot>{ req=%v }", req)
+
+	if device, err := this.deviceForId(req.Id); err != nil {
+		return nil, err
+	} else if err := this.cast.Reboot(ctx, device); err != nil {
+		return nil, toStatusError(err)
+	} else {
+		return &empty.Empty{}, nil
+	}
+}
+
+func (this *service) FactoryReset(ctx context.Context, req *pb.FactoryResetRequest) (*empty.Empty, error) {
+	this.log.Debug("<grpc.service.googlecast.FactoryReset>{ req=%v }", req)
+
+	if device, err := this.deviceForId(req.Id); err != nil {
+		return nil, err
+	} else if err := this.cast.FactoryReset(ctx, device, req.Confirm); err != nil {
+		return nil, toStatusError(err)
+	} else {
+		return &empty.Empty{}, nil
+	}
+}
+
+func (this *service) SetName(ctx context.Context, req *pb.SetNameRequest) (*empty.Empty, error) {
+	this.log.Debug("<grpc.service.googlecast.SetName>{ req=%v }", req)
+
+	if device, err := this.deviceForId(req.Id); err != nil {
+		return nil, err
+	} else if err := this.cast.SetName(ctx, device, req.Name); err != nil {
+		return nil, toStatusError(err)
+	} else {
+		return &empty.Empty{}, nil
+	}
+}
+
+func (this *service) SetTimezone(ctx context.Context, req *pb.SetTimezoneRequest) (*empty.Empty, error) {
+	this.log.Debug("<grpc.service.googlecast.SetTimezone>{ req=%v }", req)
+
+	if device, err := this.deviceForId(req.Id); err != nil {
+		return nil, err
+	} else if err := this.cast.SetTimezone(ctx, device, req.Timezone); err != nil {
+		return nil, toStatusError(err)
+	} else {
+		return &empty.Empty{}, nil
+	}
+}
+
+func (this *service) SetLocale(ctx context.Context, req *pb.SetLocaleRequest) (*empty.Empty, error) {
+	this.log.Debug("<grpc.service.googlecast.SetLocale>{ req=%v }", req)
+
+	if device, err := this.deviceForId(req.Id); err != nil {
+		return nil, err
+	} else if err := this.cast.SetLocale(ctx, device, req.Locale); err != nil {
+		return nil, toStatusError(err)
+	} else {
+		return &empty.Empty{}, nil
+	}
+}
+
+func (this *service) Connect(ctx context.Context, req *pb.DeviceRequest) (*empty.Empty, error) {
+	this.log.Debug("<grpc.service.googlecast.Connect>{ req=%v }", req)
+
+	if device, err := this.deviceForId(req.Id); err != nil {
+		return nil, err
+	} else if channel := this.channelForDevice(device); channel != nil {
+		return &empty.Empty{}, nil
+	} else if channel, err := this.cast.Connect(device, gopi.RPC_FLAG_INET_V4|gopi.RPC_FLAG_INET_V6, 0); err != nil {
+		return nil, toStatusError(err)
+	} else if this.setChannelForDevice(device, channel) == false {
+		// Another request connected in the meantime
+		this.cast.Disconnect(channel)
+		return &empty.Empty{}, nil
+	} else {
+		return &empty.Empty{}, nil
+	}
+}
+
+func (this *service) Disconnect(ctx context.Context, req *pb.DeviceRequest) (*empty.Empty, error) {
+	this.log.Debug("<grpc.service.googlecast.Disconnect>{ req=%v }", req)
+
+	if device, err := this.deviceForId(req.Id); err != nil {
+		return nil, err
+	} else if channel := this.deleteChannelForDevice(device); channel == nil {
+		return nil, status.Errorf(codes.FailedPrecondition, "Device not connected: %v", strconv.Quote(req.Id))
+	} else if err := this.cast.Disconnect(channel); err != nil {
+		return nil, toStatusError(err)
+	} else {
+		return &empty.Empty{}, nil
+	}
+}
+
+func (this *service) Status(ctx context.Context, req *pb.DeviceRequest) (*pb.CastState, error) {
+	this.log.Debug("<grpc.service.googlecast.Status>{ req=%v }", req)
+
+	if channel, err := this.channelForId(req.Id); err != nil {
+		return nil, err
+	} else {
+		return toProtoState(channel), nil
+	}
+}
+
+func (this *service) Play(ctx context.Context, req *pb.DeviceRequest) (*empty.Empty, error) {
+	this.log.Debug("<grpc.service.googlecast.Play>{ req=%v }", req)
+
+	if channel, err := this.channelForId(req.Id); err != nil {
+		return nil, err
+	} else if _, err := channel.SetPlay(true); err != nil {
+		return nil, toStatusError(err)
+	} else {
+		return &empty.Empty{}, nil
+	}
+}
+
+func (this *service) Pause(ctx context.Context, req *pb.DeviceRequest) (*empty.Empty, error) {
+	this.log.Debug("<grpc.service.googlecast.Pause>{ req=%v }", req)
+
+	if channel, err := this.channelForId(req.Id); err != nil {
+		return nil, err
+	} else if _, err := channel.SetPause(true); err != nil {
+		return nil, toStatusError(err)
+	} else {
+		return &empty.Empty{}, nil
+	}
+}
+
+func (this *service) Stop(ctx context.Context, req *pb.DeviceRequest) (*empty.Empty, error) {
+	this.log.Debug("<grpc.service.googlecast.Stop>{ req=%v }", req)
+
+	if channel, err := this.channelForId(req.Id); err != nil {
+		return nil, err
+	} else if _, err := channel.SetPlay(false); err != nil {
+		return nil, toStatusError(err)
+	} else {
+		return &empty.Empty{}, nil
+	}
+}
+
+func (this *service) Seek(ctx context.Context, req *pb.SeekRequest) (*empty.Empty, error) {
+	this.log.Debug("<grpc.service.googlecast.Seek>{ req=%v }", req)
+
+	if channel, err := this.channelForId(req.Id); err != nil {
+		return nil, err
+	} else if _, err := channel.SetSeek(req.Position); err != nil {
+		return nil, toStatusError(err)
+	} else {
+		return &empty.Empty{}, nil
+	}
+}
+
+func (this *service) SetVolume(ctx context.Context, req *pb.SetVolumeRequest) (*empty.Empty, error) {
+	this.log.Debug("<grpc.service.googlecast.SetVolume>{ req=%v }", req)
+
+	if channel, err := this.channelForId(req.Id); err != nil {
+		return nil, err
+	} else if _, err := channel.SetVolume(req.Level); err != nil {
+		return nil, toStatusError(err)
+	} else {
+		return &empty.Empty{}, nil
+	}
+}
+
+func (this *service) SetMuted(ctx context.Context, req *pb.SetMutedRequest) (*empty.Empty, error) {
+	this.log.Debug("<grpc.service.googlecast.SetMuted>{ req=%v }", req)
+
+	if channel, err := this.channelForId(req.Id); err != nil {
+		return nil, err
+	} else if _, err := channel.SetMuted(req.Muted); err != nil {
+		return nil, toStatusError(err)
+	} else {
+		return &empty.Empty{}, nil
+	}
+}
+
+func (this *service) LaunchApp(ctx context.Context, req *pb.LaunchAppRequest) (*empty.Empty, error) {
+	this.log.Debug("<grpc.service.googlecast.LaunchApp>{ req=%v }", req)
+
+	if channel, err := this.channelForId(req.Id); err != nil {
+		return nil, err
+	} else if _, err := channel.LaunchApp(req.AppId); err != nil {
+		return nil, toStatusError(err)
+	} else {
+		return &empty.Empty{}, nil
+	}
+}
+
+func (this *service) StopApp(ctx context.Context, req *pb.DeviceRequest) (*empty.Empty, error) {
+	this.log.Debug("<grpc.service.googlecast.StopApp>{ req=%v }", req)
+
+	if channel, err := this.channelForId(req.Id); err != nil {
+		return nil, err
+	} else if _, err := channel.StopApp(); err != nil {
+		return nil, toStatusError(err)
+	} else {
+		return &empty.Empty{}, nil
+	}
+}
+
+func (this *service) LoadMedia(ctx context.Context, req *pb.LoadMediaRequest) (*empty.Empty, error) {
+	this.log.Debug("<grpc.service.googlecast.LoadMedia>{ req=%v }", req)
+
+	if channel, err := this.channelForId(req.Id); err != nil {
+		return nil, err
+	} else if _, err := channel.LoadMedia(fromProtoLoadMediaRequest(req)); err != nil {
+		return nil, toStatusError(err)
+	} else {
+		return &empty.Empty{}, nil
+	}
 }
 
 ////////////////////////////////////////////////////////////////////////////////
 // BACKGROUND TASKS
 
 func (this *service) EventsTask(start chan<- event.Signal, stop <-chan event.Signal) error {
-	evt := this.cast.Subscribe()
+	// Subscribe to devices being added and removed, dropping the oldest
+	// events rather than blocking the emitter when actions are slow
+	evt := this.cast.SubscribeEvents(googlecast.SubscribeOptions{
+		Types: []googlecast.EventType{googlecast.CAST_EVENT_DEVICE_ADDED, googlecast.CAST_EVENT_DEVICE_DELETED},
+	})
 	start <- gopi.DONE
 
 FOR_LOOP:
 	for {
 		select {
-		case event_ := <-evt:
-			if event, ok := event_.(googlecast.Event); ok && event != nil {
-				if err := this.EventAction(event); err != nil {
-					this.log.Warn("EventAction: %v", err)
-				}
+		case event, ok := <-evt:
+			if ok == false {
+				// Channel closed when cast is closed
+				evt = nil
+			} else if err := this.EventAction(event); err != nil {
+				this.log.Warn("EventAction: %v", err)
+				this.Emit(&errorevent{event, err})
 			}
 		case <-stop:
-			this.cast.Unsubscribe(evt)
+			this.cast.UnsubscribeEvents(evt)
 			break FOR_LOOP
 		}
 	}
@@ -200,13 +444,21 @@ func (this *service) EventAction(event googlecast.Event) error {
 		if channel, err := this.cast.Connect(event.Device(), gopi.RPC_FLAG_INET_V4|gopi.RPC_FLAG_INET_V6, 0); err != nil {
 			return err
 		} else if this.setChannelForDevice(event.Device(), channel) == false {
+			// Disconnect the channel which could not be retained
+			if err := this.cast.Disconnect(channel); err != nil {
+				return err
+			}
 			return gopi.ErrAppError
 		} else {
 			fmt.Println("CONNECT", channel)
 		}
 	case googlecast.CAST_EVENT_DEVICE_DELETED:
-		if channel := this.channelForDevice(event.Device()); channel != nil {
-			if err := this.cast.Disconnect(channel); err != nil {
+		// A channel to an expired device is disconnected before the
+		// device is deleted, so is not found when disconnected here
+		if channel := this.deleteChannelForDevice(event.Device()); channel != nil {
+			if err := this.cast.Disconnect(channel); errors.Is(err, gopi.ErrNotFound) {
+				// Already disconnected
+			} else if err != nil {
 				return err
 			} else {
 				fmt.Println("DISCONNECT", channel)
@@ -217,9 +469,82 @@ func (this *service) EventAction(event googlecast.Event) error {
 	return nil
 }
 
+////////////////////////////////////////////////////////////////////////////////
+// ERROR EVENT IMPLEMENTATION
+
+func (this *errorevent) Err() error {
+	return this.err
+}
+
+func (this *errorevent) String() string {
+	return fmt.Sprintf("<grpc.service.googlecast.ErrorEvent>{ %v err=%v }", this.Event, this.err)
+}
+
 ////////////////////////////////////////////////////////////////////////////////
 // PRIVATE METHODS
 
+// send an event to a stream with the epoch of the service
+func (this *service) send(stream pb.GoogleCast_StreamEventsServer, evt *pb.CastEvent) error {
+	evt.Epoch = this.epoch
+	return stream.Send(evt)
+}
+
+// deviceForId returns a device or a NotFound error
+func (this *service) deviceForId(id string) (googlecast.Device, error) {
+	if device := this.cast.DeviceById(id); device == nil {
+		return nil, status.Errorf(codes.NotFound, "Device not found: %v", strconv.Quote(id))
+	} else {
+		return device, nil
+	}
+}
+
+// channelForId returns the channel for a connected device, or a NotFound
+// or FailedPrecondition error when the device is not connected
+func (this *service) channelForId(id string) (googlecast.Channel, error) {
+	if device, err := this.deviceForId(id); err != nil {
+		return nil, err
+	} else if channel := this.channelForDevice(device); channel == nil {
+		return nil, status.Errorf(codes.FailedPrecondition, "Device not connected: %v", strconv.Quote(id))
+	} else {
+		return channel, nil
+	}
+}
+
+// toStatusError returns a gRPC status error for an error. Errors
+// reading from or writing to a dropped connection are unavailable
+func toStatusError(err error) error {
+	var unsupported *googlecast.UnsupportedCommandError
+	var neterr *net.OpError
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, googlecast.ErrManagementDisabled):
+		return status.Error(codes.PermissionDenied, err.Error())
+	case errors.Is(err, gopi.ErrBadParameter):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, gopi.ErrNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, gopi.ErrOutOfOrder):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, googlecast.ErrUnsupportedNamespace):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.As(err, &unsupported):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, gopi.ErrNotImplemented):
+		return status.Error(codes.Unimplemented, err.Error())
+	case errors.Is(err, context.DeadlineExceeded):
+		return status.Error(codes.DeadlineExceeded, err.Error())
+	case errors.Is(err, context.Canceled):
+		return status.Error(codes.Canceled, err.Error())
+	case errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF), errors.Is(err, io.ErrClosedPipe):
+		return status.Error(codes.Unavailable, err.Error())
+	case errors.Is(err, syscall.EPIPE), errors.Is(err, syscall.ECONNRESET), errors.As(err, &neterr):
+		return status.Error(codes.Unavailable, err.Error())
+	default:
+		return status.Error(codes.Unknown, err.Error())
+	}
+}
+
 func (this *service) channelForDevice(device googlecast.Device) googlecast.Channel {
 	this.Mutex.Lock()
 	defer this.Mutex.Unlock()
@@ -235,7 +560,7 @@ func (this *service) channelForDevice(device googlecast.Device) googlecast.Chann
 func (this *service) setChannelForDevice(device googlecast.Device, channel googlecast.Channel) bool {
 	this.Mutex.Lock()
 	defer this.Mutex.Unlock()
-	if channel != nil || device != nil {
+	if channel == nil || device == nil {
 		return false
 	} else if _, exists := this.channel[device.Id()]; exists {
 		return false
@@ -244,3 +569,16 @@ func (this *service) setChannelForDevice(device googlecast.Device, channel googl
 		return true
 	}
 }
+
+func (this *service) deleteChannelForDevice(device googlecast.Device) googlecast.Channel {
+	this.Mutex.Lock()
+	defer this.Mutex.Unlock()
+	if device == nil {
+		return nil
+	} else if channel, exists := this.channel[device.Id()]; exists {
+		delete(this.channel, device.Id())
+		return channel
+	} else {
+		return nil
+	}
+}
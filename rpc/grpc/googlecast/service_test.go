@@ -0,0 +1,271 @@
+package googlecast
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"syscall"
+	"testing"
+	"time"
+
+	// Frameworks
+	googlecast "github.com/djthorpe/googlecast"
+	gopi "github.com/djthorpe/gopi"
+	logger "github.com/djthorpe/gopi/sys/logger"
+
+	// Protocol buffers
+	pb "github.com/djthorpe/googlecast/rpc/protobuf/googlecast"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// testcast implements the driver methods used by the service
+type testcast struct {
+	googlecast.Cast
+	devices    map[string]googlecast.Device
+	disconnect error
+	events     chan googlecast.Event
+	options    googlecast.SubscribeOptions
+}
+
+// teststream records events sent to a client
+type teststream struct {
+	pb.GoogleCast_StreamEventsServer
+	events []*pb.CastEvent
+}
+
+type testerrorevent struct {
+	googlecast.Event
+	err error
+}
+
+type testdevice struct {
+	googlecast.Device
+	id string
+}
+
+type testchannel struct {
+	googlecast.Channel
+}
+
+type testevent struct {
+	googlecast.Event
+	type_  googlecast.EventType
+	device googlecast.Device
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// EVENTS
+
+func TestEventAction_000(t *testing.T) {
+	// A channel which was disconnected before the device was deleted
+	// is not an error
+	for _, err := range []error{nil, gopi.ErrNotFound} {
+		device := &testdevice{id: "a1b2c3"}
+		this := testService(&testcast{disconnect: err}, device)
+		this.setChannelForDevice(device, &testchannel{})
+		if err := this.EventAction(&testevent{type_: googlecast.CAST_EVENT_DEVICE_DELETED, device: device}); err != nil {
+			t.Error("Unexpected error", err)
+		} else if this.channelForDevice(device) != nil {
+			t.Error("Expected channel to be removed")
+		}
+	}
+}
+
+func TestEventAction_001(t *testing.T) {
+	// Other errors disconnecting are returned
+	device := &testdevice{id: "a1b2c3"}
+	this := testService(&testcast{disconnect: gopi.ErrAppError}, device)
+	this.setChannelForDevice(device, &testchannel{})
+	if err := this.EventAction(&testevent{type_: googlecast.CAST_EVENT_DEVICE_DELETED, device: device}); err != gopi.ErrAppError {
+		t.Error("Unexpected error", err)
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// CONNECT AND DISCONNECT
+
+func TestDisconnect_000(t *testing.T) {
+	device := &testdevice{id: "a1b2c3"}
+	this := testService(&testcast{}, device)
+	this.log = testLogger(t)
+
+	// Unknown device is not found, and a known device which is not
+	// connected fails the precondition
+	if _, err := this.Disconnect(context.Background(), &pb.DeviceRequest{Id: "d4e5f6"}); status.Code(err) != codes.NotFound {
+		t.Error("Unexpected error", err)
+	} else if _, err := this.Disconnect(context.Background(), &pb.DeviceRequest{Id: device.id}); status.Code(err) != codes.FailedPrecondition {
+		t.Error("Unexpected error", err)
+	} else if _, err := this.Status(context.Background(), &pb.DeviceRequest{Id: device.id}); status.Code(err) != codes.FailedPrecondition {
+		t.Error("Unexpected error", err)
+	}
+
+	// Connected device is disconnected
+	this.setChannelForDevice(device, &testchannel{})
+	if _, err := this.Disconnect(context.Background(), &pb.DeviceRequest{Id: device.id}); err != nil {
+		t.Error("Unexpected error", err)
+	} else if this.channelForDevice(device) != nil {
+		t.Error("Expected channel to be removed")
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// STATUS ERRORS
+
+func TestStatusError_000(t *testing.T) {
+	tests := []struct {
+		err      error
+		expected codes.Code
+	}{
+		{nil, codes.OK},
+		{gopi.ErrOutOfOrder, codes.FailedPrecondition},
+		{fmt.Errorf("LoadMedia: %w", gopi.ErrOutOfOrder), codes.FailedPrecondition},
+		{gopi.ErrBadParameter, codes.InvalidArgument},
+		{gopi.ErrNotFound, codes.NotFound},
+		{gopi.ErrNotImplemented, codes.Unimplemented},
+		{googlecast.ErrManagementDisabled, codes.PermissionDenied},
+		{googlecast.ErrUnsupportedNamespace, codes.FailedPrecondition},
+		{&googlecast.UnsupportedCommandError{Command: googlecast.CAST_MEDIA_COMMAND_PAUSE}, codes.FailedPrecondition},
+		{fmt.Errorf("Pause: %w", &googlecast.UnsupportedCommandError{Command: googlecast.CAST_MEDIA_COMMAND_PAUSE}), codes.FailedPrecondition},
+		{context.DeadlineExceeded, codes.DeadlineExceeded},
+		{context.Canceled, codes.Canceled},
+		{io.EOF, codes.Unavailable},
+		{&net.OpError{Op: "write", Net: "tcp", Err: syscall.EPIPE}, codes.Unavailable},
+		{&net.OpError{Op: "read", Net: "tcp", Err: errors.New("connection reset")}, codes.Unavailable},
+		{syscall.ECONNRESET, codes.Unavailable},
+		{gopi.ErrAppError, codes.Unknown},
+	}
+	for _, test := range tests {
+		if err := toStatusError(test.err); status.Code(err) != test.expected {
+			t.Errorf("%v: Unexpected code %v, expected %v", test.err, status.Code(err), test.expected)
+		}
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// STREAM EVENTS
+
+func TestStreamEvents_000(t *testing.T) {
+	// The stream ends with ResourceExhausted when the subscription overflows
+	cast := &testcast{events: make(chan googlecast.Event, 2)}
+	this := testService(cast)
+	this.log = testLogger(t)
+	stream := &teststream{}
+
+	cast.events <- &testevent{type_: googlecast.CAST_EVENT_VOLUME_UPDATED}
+	cast.events <- &testerrorevent{err: googlecast.ErrOverflow}
+	if err := this.StreamEvents(&pb.StreamEventsRequest{}, stream); status.Code(err) != codes.ResourceExhausted {
+		t.Error("Unexpected error", err)
+	} else if len(stream.events) != 1 || stream.events[0].Type != pb.CastEvent_VOLUME_UPDATED {
+		t.Error("Unexpected events", stream.events)
+	} else if stream.events[0].Epoch != this.epoch {
+		t.Error("Unexpected epoch", stream.events[0].Epoch)
+	} else if cast.options.Overflow != googlecast.CAST_OVERFLOW_CLOSE {
+		t.Error("Unexpected overflow policy", cast.options.Overflow)
+	}
+}
+
+func TestStreamEvents_001(t *testing.T) {
+	// The stream ends without error when the subscription is closed
+	cast := &testcast{events: make(chan googlecast.Event)}
+	this := testService(cast)
+	this.log = testLogger(t)
+	close(cast.events)
+	if err := this.StreamEvents(&pb.StreamEventsRequest{}, &teststream{}); err != nil {
+		t.Error("Unexpected error", err)
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// UTILS
+
+// testLogger returns a logger which discards output
+func testLogger(t *testing.T) gopi.Logger {
+	t.Helper()
+	if log, err := gopi.Open(logger.Config{Level: logger.LOG_NONE}, nil); err != nil {
+		t.Fatal(err)
+		return nil
+	} else {
+		return log.(gopi.Logger)
+	}
+}
+
+// testService returns a service without background tasks for a driver
+// with the devices provided
+func testService(cast *testcast, devices ...googlecast.Device) *service {
+	cast.devices = make(map[string]googlecast.Device)
+	for _, device := range devices {
+		cast.devices[device.Id()] = device
+	}
+	return &service{cast: cast, channel: make(map[string]googlecast.Channel)}
+}
+
+func (this *testcast) DeviceById(id string) googlecast.Device {
+	if device, exists := this.devices[id]; exists {
+		return device
+	} else {
+		return nil
+	}
+}
+
+func (this *testcast) Disconnect(googlecast.Channel) error {
+	return this.disconnect
+}
+
+func (this *testcast) SubscribeEvents(options googlecast.SubscribeOptions) <-chan googlecast.Event {
+	this.options = options
+	return this.events
+}
+
+func (this *testcast) UnsubscribeEvents(<-chan googlecast.Event) {
+}
+
+func (this *teststream) Send(evt *pb.CastEvent) error {
+	this.events = append(this.events, evt)
+	return nil
+}
+
+func (this *testerrorevent) Err() error {
+	return this.err
+}
+
+func (this *testdevice) Id() string {
+	return this.id
+}
+
+func (this *testevent) Type() googlecast.EventType {
+	return this.type_
+}
+
+func (this *testevent) Device() googlecast.Device {
+	return this.device
+}
+
+func (this *testevent) Channel() googlecast.Channel {
+	return nil
+}
+
+func (this *testevent) Sequence() uint64 {
+	return 0
+}
+
+func (this *testevent) Timestamp() time.Time {
+	return time.Time{}
+}
+
+func (this *testevent) State() googlecast.State {
+	return nil
+}
+
+func (this *testevent) Previous() googlecast.State {
+	return nil
+}
+
+func (this *testevent) String() string {
+	return fmt.Sprint(this.type_)
+}
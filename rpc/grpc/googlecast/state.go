@@ -0,0 +1,547 @@
+/*
+	Go Language Raspberry Pi Interface
+	(c) Copyright David Thorpe 2019
+	All Rights Reserved
+	Documentation http://djthorpe.github.io/gopi/
+	For Licensing and Usage information, please see LICENSE.md
+*/
+
+package googlecast
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	// Frameworks
+	googlecast "github.com/djthorpe/googlecast"
+
+	// Protocol buffers
+	pb "github.com/djthorpe/googlecast/rpc/protobuf/googlecast"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+type caststate struct {
+	*pb.CastState
+	received time.Time
+}
+
+type castapplication struct {
+	*pb.CastApplication
+}
+
+type castvolume struct {
+	*pb.CastVolume
+}
+
+// castmedia estimates the position from the time the
+// message was received
+type castmedia struct {
+	*pb.CastMedia
+	received time.Time
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// STATE IMPLEMENTATION
+
+func (this *caststate) Application() googlecast.Application {
+	if this.CastState == nil || this.CastState.Application == nil {
+		return nil
+	} else {
+		return &castapplication{this.CastState.Application}
+	}
+}
+
+func (this *caststate) Volume() googlecast.Volume {
+	if this.CastState == nil || this.CastState.Volume == nil {
+		return nil
+	} else {
+		return &castvolume{this.CastState.Volume}
+	}
+}
+
+func (this *caststate) Media() googlecast.Media {
+	if this.CastState == nil || this.CastState.Media == nil {
+		return nil
+	} else {
+		return &castmedia{this.CastState.Media, this.received}
+	}
+}
+
+func (this *caststate) ActiveInput() bool {
+	return this.GetActiveInput()
+}
+
+func (this *caststate) StandBy() bool {
+	return this.GetStandby()
+}
+
+func (this *caststate) String() string {
+	return fmt.Sprintf("<googlecast.State>{ app=%v volume=%v media=%v active_input=%v standby=%v }", this.Application(), this.Volume(), this.Media(), this.ActiveInput(), this.StandBy())
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// APPLICATION IMPLEMENTATION
+
+func (this *castapplication) ID() string {
+	return this.GetId()
+}
+
+func (this *castapplication) Name() string {
+	return this.GetName()
+}
+
+func (this *castapplication) Status() string {
+	return this.GetStatus()
+}
+
+func (this *castapplication) Type() string {
+	return this.GetType()
+}
+
+func (this *castapplication) UniversalID() string {
+	return this.GetUniversalId()
+}
+
+func (this *castapplication) Namespaces() []string {
+	return this.GetNamespaces()
+}
+
+func (this *castapplication) SupportsNamespace(ns string) bool {
+	if len(this.GetNamespaces()) == 0 {
+		return true
+	}
+	for _, namespace := range this.GetNamespaces() {
+		if namespace == ns {
+			return true
+		}
+	}
+	return false
+}
+
+func (this *castapplication) SessionId() string {
+	return this.GetSessionId()
+}
+
+func (this *castapplication) IdleScreen() bool {
+	return this.GetIdleScreen()
+}
+
+func (this *castapplication) SessionStarted() time.Time {
+	return fromProtoTimestamp(this.GetSessionStarted())
+}
+
+func (this *castapplication) String() string {
+	return fmt.Sprintf("<googlecast.Application>{ id=%v name=%v status=%v session_id=%v }", strconv.Quote(this.ID()), strconv.Quote(this.Name()), strconv.Quote(this.Status()), strconv.Quote(this.SessionId()))
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// VOLUME IMPLEMENTATION
+
+func (this *castvolume) Level() float32 {
+	return this.GetLevel()
+}
+
+func (this *castvolume) Muted() bool {
+	return this.GetMuted()
+}
+
+func (this *castvolume) ControlType() string {
+	return this.GetControlType()
+}
+
+func (this *castvolume) StepInterval() float32 {
+	return this.GetStepInterval()
+}
+
+func (this *castvolume) String() string {
+	return fmt.Sprintf("<googlecast.Volume>{ level=%.2f muted=%v }", this.Level(), this.Muted())
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// MEDIA IMPLEMENTATION
+
+func (this *castmedia) PlayerState() googlecast.PlayerState {
+	return googlecast.PlayerState(this.GetPlayerState())
+}
+
+func (this *castmedia) IdleReason() string {
+	return this.GetIdleReason()
+}
+
+func (this *castmedia) ContentId() string {
+	return this.GetContentId()
+}
+
+func (this *castmedia) ContentType() string {
+	return this.GetContentType()
+}
+
+func (this *castmedia) Metadata() googlecast.MediaMetadata {
+	return fromProtoMetadata(this.GetMetadata())
+}
+
+func (this *castmedia) Tracks() []googlecast.Track {
+	return fromProtoTracks(this.GetTracks())
+}
+
+func (this *castmedia) ActiveTrackIds() []int {
+	return fromProtoTrackIds(this.GetActiveTrackIds())
+}
+
+func (this *castmedia) TextTrackStyle() *googlecast.TextTrackStyle {
+	return fromProtoTextTrackStyle(this.GetTextTrackStyle())
+}
+
+func (this *castmedia) SupportedCommands() googlecast.MediaCommand {
+	return googlecast.MediaCommand(this.GetSupportedCommands())
+}
+
+func (this *castmedia) Supports(cmd googlecast.MediaCommand) bool {
+	return this.SupportedCommands()&cmd == cmd
+}
+
+func (this *castmedia) StreamType() googlecast.StreamType {
+	return googlecast.StreamType(this.GetStreamType())
+}
+
+func (this *castmedia) LiveSeekableRange() (float32, float32, bool) {
+	return this.GetLiveStart(), this.GetLiveEnd(), this.GetLive()
+}
+
+func (this *castmedia) LiveDone() bool {
+	return this.GetLiveDone()
+}
+
+func (this *castmedia) BehindLiveEdge() float32 {
+	return this.GetBehindLiveEdge()
+}
+
+func (this *castmedia) PlaybackRate() float32 {
+	if rate := this.GetPlaybackRate(); rate == 0 {
+		return 1
+	} else {
+		return rate
+	}
+}
+
+func (this *castmedia) EstimatedPosition() float32 {
+	if this.PlayerState() != googlecast.CAST_PLAYER_STATE_PLAYING || this.received.IsZero() {
+		return this.GetPosition()
+	} else {
+		return this.GetPosition() + float32(time.Since(this.received).Seconds())*this.PlaybackRate()
+	}
+}
+
+func (this *castmedia) String() string {
+	return fmt.Sprintf("<googlecast.Media>{ state=%v content_id=%v position=%v }", this.PlayerState(), strconv.Quote(this.ContentId()), this.EstimatedPosition())
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// FROM PROTO
+
+func fromProtoState(pb *pb.CastState) googlecast.State {
+	if pb == nil {
+		return nil
+	} else {
+		return &caststate{pb, time.Now()}
+	}
+}
+
+func fromProtoMetadata(pb *pb.CastMetadata) googlecast.MediaMetadata {
+	if pb == nil {
+		return nil
+	}
+	images := make([]googlecast.Image, len(pb.GetImages()))
+	for i, image := range pb.GetImages() {
+		images[i] = googlecast.Image{URL: image.GetUrl(), Width: int(image.GetWidth()), Height: int(image.GetHeight())}
+	}
+	switch googlecast.MetadataType(pb.GetType()) {
+	case googlecast.CAST_METADATA_TYPE_MOVIE:
+		return googlecast.MovieMetadata{
+			Title:       pb.GetTitle(),
+			Subtitle:    pb.GetSubtitle(),
+			Studio:      pb.GetStudio(),
+			Images:      images,
+			ReleaseDate: pb.GetReleaseDate(),
+		}
+	case googlecast.CAST_METADATA_TYPE_TV_SHOW:
+		return googlecast.TvShowMetadata{
+			Title:           pb.GetTitle(),
+			SeriesTitle:     pb.GetSeriesTitle(),
+			Season:          int(pb.GetSeason()),
+			Episode:         int(pb.GetEpisode()),
+			Images:          images,
+			OriginalAirdate: pb.GetReleaseDate(),
+		}
+	case googlecast.CAST_METADATA_TYPE_MUSIC_TRACK:
+		return googlecast.MusicTrackMetadata{
+			Title:       pb.GetTitle(),
+			AlbumName:   pb.GetAlbumName(),
+			AlbumArtist: pb.GetAlbumArtist(),
+			Artist:      pb.GetArtist(),
+			Composer:    pb.GetComposer(),
+			TrackNumber: int(pb.GetTrackNumber()),
+			DiscNumber:  int(pb.GetDiscNumber()),
+			Images:      images,
+			ReleaseDate: pb.GetReleaseDate(),
+		}
+	case googlecast.CAST_METADATA_TYPE_PHOTO:
+		return googlecast.PhotoMetadata{
+			Title:            pb.GetTitle(),
+			Artist:           pb.GetArtist(),
+			Location:         pb.GetLocation(),
+			Latitude:         pb.GetLatitude(),
+			Longitude:        pb.GetLongitude(),
+			Width:            int(pb.GetWidth()),
+			Height:           int(pb.GetHeight()),
+			CreationDateTime: pb.GetReleaseDate(),
+		}
+	default:
+		return googlecast.GenericMetadata{
+			Title:       pb.GetTitle(),
+			Subtitle:    pb.GetSubtitle(),
+			Images:      images,
+			ReleaseDate: pb.GetReleaseDate(),
+		}
+	}
+}
+
+func fromProtoTracks(pb []*pb.CastTrack) []googlecast.Track {
+	tracks := make([]googlecast.Track, len(pb))
+	for i, track := range pb {
+		tracks[i] = googlecast.Track{
+			Id:          int(track.GetId()),
+			Type:        googlecast.TrackType(track.GetType()),
+			ContentId:   track.GetContentId(),
+			ContentType: track.GetContentType(),
+			Subtype:     track.GetSubtype(),
+			Name:        track.GetName(),
+			Language:    track.GetLanguage(),
+		}
+	}
+	return tracks
+}
+
+func fromProtoTrackIds(pb []int32) []int {
+	ids := make([]int, len(pb))
+	for i, id := range pb {
+		ids[i] = int(id)
+	}
+	return ids
+}
+
+func fromProtoTextTrackStyle(pb *pb.CastTextTrackStyle) *googlecast.TextTrackStyle {
+	if pb == nil {
+		return nil
+	}
+	return &googlecast.TextTrackStyle{
+		FontScale:       pb.GetFontScale(),
+		FontFamily:      pb.GetFontFamily(),
+		ForegroundColor: pb.GetForegroundColor(),
+		BackgroundColor: pb.GetBackgroundColor(),
+		EdgeType:        googlecast.EdgeType(pb.GetEdgeType()),
+		EdgeColor:       pb.GetEdgeColor(),
+	}
+}
+
+func fromProtoLoadMediaRequest(pb *pb.LoadMediaRequest) googlecast.MediaRequest {
+	return googlecast.MediaRequest{
+		ContentId:      pb.GetContentId(),
+		ContentType:    pb.GetContentType(),
+		StreamType:     googlecast.StreamType(pb.GetStreamType()),
+		Duration:       pb.GetDuration(),
+		Metadata:       fromProtoMetadata(pb.GetMetadata()),
+		Autoplay:       pb.GetAutoplay(),
+		CurrentTime:    pb.GetCurrentTime(),
+		Tracks:         fromProtoTracks(pb.GetTracks()),
+		ActiveTrackIds: fromProtoTrackIds(pb.GetActiveTrackIds()),
+		TextTrackStyle: fromProtoTextTrackStyle(pb.GetTextTrackStyle()),
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// TO PROTO
+
+func toProtoState(state googlecast.State) *pb.CastState {
+	if state == nil {
+		return nil
+	}
+	return &pb.CastState{
+		Application: toProtoApplication(state.Application()),
+		Volume:      toProtoVolume(state.Volume()),
+		Media:       toProtoMedia(state.Media()),
+		ActiveInput: state.ActiveInput(),
+		Standby:     state.StandBy(),
+	}
+}
+
+func toProtoApplication(app googlecast.Application) *pb.CastApplication {
+	if app == nil {
+		return nil
+	}
+	return &pb.CastApplication{
+		Id:             app.ID(),
+		Name:           app.Name(),
+		Status:         app.Status(),
+		Type:           app.Type(),
+		UniversalId:    app.UniversalID(),
+		Namespaces:     app.Namespaces(),
+		SessionId:      app.SessionId(),
+		IdleScreen:     app.IdleScreen(),
+		SessionStarted: toProtoTimestamp(app.SessionStarted()),
+	}
+}
+
+func toProtoVolume(volume googlecast.Volume) *pb.CastVolume {
+	if volume == nil {
+		return nil
+	}
+	return &pb.CastVolume{
+		Level:        volume.Level(),
+		Muted:        volume.Muted(),
+		ControlType:  volume.ControlType(),
+		StepInterval: volume.StepInterval(),
+	}
+}
+
+func toProtoMedia(media googlecast.Media) *pb.CastMedia {
+	if media == nil {
+		return nil
+	}
+	start, end, live := media.LiveSeekableRange()
+	return &pb.CastMedia{
+		PlayerState:       uint32(media.PlayerState()),
+		IdleReason:        media.IdleReason(),
+		ContentId:         media.ContentId(),
+		ContentType:       media.ContentType(),
+		StreamType:        uint32(media.StreamType()),
+		SupportedCommands: uint32(media.SupportedCommands()),
+		Position:          media.EstimatedPosition(),
+		PlaybackRate:      media.PlaybackRate(),
+		Live:              live,
+		LiveStart:         start,
+		LiveEnd:           end,
+		LiveDone:          media.LiveDone(),
+		BehindLiveEdge:    media.BehindLiveEdge(),
+		Metadata:          toProtoMetadata(media.Metadata()),
+		Tracks:            toProtoTracks(media.Tracks()),
+		ActiveTrackIds:    toProtoTrackIds(media.ActiveTrackIds()),
+		TextTrackStyle:    toProtoTextTrackStyle(media.TextTrackStyle()),
+	}
+}
+
+func toProtoMetadata(metadata googlecast.MediaMetadata) *pb.CastMetadata {
+	if metadata == nil {
+		return nil
+	}
+	reply := &pb.CastMetadata{
+		Type: uint32(metadata.Type()),
+	}
+	var images []googlecast.Image
+	switch metadata_ := metadata.(type) {
+	case googlecast.GenericMetadata:
+		reply.Title = metadata_.Title
+		reply.Subtitle = metadata_.Subtitle
+		reply.ReleaseDate = metadata_.ReleaseDate
+		images = metadata_.Images
+	case googlecast.MovieMetadata:
+		reply.Title = metadata_.Title
+		reply.Subtitle = metadata_.Subtitle
+		reply.Studio = metadata_.Studio
+		reply.ReleaseDate = metadata_.ReleaseDate
+		images = metadata_.Images
+	case googlecast.TvShowMetadata:
+		reply.Title = metadata_.Title
+		reply.SeriesTitle = metadata_.SeriesTitle
+		reply.Season = int32(metadata_.Season)
+		reply.Episode = int32(metadata_.Episode)
+		reply.ReleaseDate = metadata_.OriginalAirdate
+		images = metadata_.Images
+	case googlecast.MusicTrackMetadata:
+		reply.Title = metadata_.Title
+		reply.AlbumName = metadata_.AlbumName
+		reply.AlbumArtist = metadata_.AlbumArtist
+		reply.Artist = metadata_.Artist
+		reply.Composer = metadata_.Composer
+		reply.TrackNumber = int32(metadata_.TrackNumber)
+		reply.DiscNumber = int32(metadata_.DiscNumber)
+		reply.ReleaseDate = metadata_.ReleaseDate
+		images = metadata_.Images
+	case googlecast.PhotoMetadata:
+		reply.Title = metadata_.Title
+		reply.Artist = metadata_.Artist
+		reply.Location = metadata_.Location
+		reply.Latitude = metadata_.Latitude
+		reply.Longitude = metadata_.Longitude
+		reply.Width = int32(metadata_.Width)
+		reply.Height = int32(metadata_.Height)
+		reply.ReleaseDate = metadata_.CreationDateTime
+	}
+	for _, image := range images {
+		reply.Images = append(reply.Images, &pb.CastImage{Url: image.URL, Width: int32(image.Width), Height: int32(image.Height)})
+	}
+	return reply
+}
+
+func toProtoTracks(tracks []googlecast.Track) []*pb.CastTrack {
+	if len(tracks) == 0 {
+		return nil
+	}
+	reply := make([]*pb.CastTrack, len(tracks))
+	for i, track := range tracks {
+		reply[i] = &pb.CastTrack{
+			Id:          int32(track.Id),
+			Type:        uint32(track.Type),
+			ContentId:   track.ContentId,
+			ContentType: track.ContentType,
+			Subtype:     track.Subtype,
+			Name:        track.Name,
+			Language:    track.Language,
+		}
+	}
+	return reply
+}
+
+func toProtoTrackIds(ids []int) []int32 {
+	if len(ids) == 0 {
+		return nil
+	}
+	reply := make([]int32, len(ids))
+	for i, id := range ids {
+		reply[i] = int32(id)
+	}
+	return reply
+}
+
+func toProtoTextTrackStyle(style *googlecast.TextTrackStyle) *pb.CastTextTrackStyle {
+	if style == nil {
+		return nil
+	}
+	return &pb.CastTextTrackStyle{
+		FontScale:       style.FontScale,
+		FontFamily:      style.FontFamily,
+		ForegroundColor: style.ForegroundColor,
+		BackgroundColor: style.BackgroundColor,
+		EdgeType:        uint32(style.EdgeType),
+		EdgeColor:       style.EdgeColor,
+	}
+}
+
+func toProtoLoadMediaRequest(id string, request googlecast.MediaRequest) *pb.LoadMediaRequest {
+	return &pb.LoadMediaRequest{
+		Id:             id,
+		ContentId:      request.ContentId,
+		ContentType:    request.ContentType,
+		StreamType:     uint32(request.StreamType),
+		Duration:       request.Duration,
+		Metadata:       toProtoMetadata(request.Metadata),
+		Autoplay:       request.Autoplay,
+		CurrentTime:    request.CurrentTime,
+		Tracks:         toProtoTracks(request.Tracks),
+		ActiveTrackIds: toProtoTrackIds(request.ActiveTrackIds),
+		TextTrackStyle: toProtoTextTrackStyle(request.TextTrackStyle),
+	}
+}
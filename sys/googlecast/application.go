@@ -11,18 +11,29 @@ package googlecast
 import (
 	"fmt"
 	"strconv"
+	"time"
 )
 
 ////////////////////////////////////////////////////////////////////////////////
 // TYPES
 
 type application struct {
-	AppId        string `json:"appId"`
-	DisplayName  string `json:"displayName"`
-	IsIdleScreen bool   `json:"isIdleScreen"`
-	SessionId    string `json:"sessionId"`
-	StatusText   string `json:"statusText"`
-	TransportId  string `json:"transportId"`
+	AppId          string                 `json:"appId"`
+	AppType        string                 `json:"appType"`
+	UniversalAppId string                 `json:"universalAppId"`
+	DisplayName    string                 `json:"displayName"`
+	IsIdleScreen   bool                   `json:"isIdleScreen"`
+	Namespaces_    []applicationNamespace `json:"namespaces"`
+	SessionId_     string                 `json:"sessionId"`
+	StatusText     string                 `json:"statusText"`
+	TransportId    string                 `json:"transportId"`
+
+	// Time the session was first seen
+	started time.Time
+}
+
+type applicationNamespace struct {
+	Name string `json:"name"`
 }
 
 ////////////////////////////////////////////////////////////////////////////////
@@ -40,17 +51,75 @@ func (this *application) Status() string {
 	return this.StatusText
 }
 
+func (this *application) Type() string {
+	return this.AppType
+}
+
+func (this *application) UniversalID() string {
+	return this.UniversalAppId
+}
+
+func (this *application) SessionId() string {
+	return this.SessionId_
+}
+
+func (this *application) IdleScreen() bool {
+	return this.IsIdleScreen
+}
+
+func (this *application) SessionStarted() time.Time {
+	return this.started
+}
+
+// session returns the session identifier for an application which is
+// not the idle screen, or an empty string
+func (this *application) session() string {
+	if this == nil || this.IsIdleScreen {
+		return ""
+	} else {
+		return this.SessionId_
+	}
+}
+
+func (this *application) Namespaces() []string {
+	namespaces := make([]string, len(this.Namespaces_))
+	for i, namespace := range this.Namespaces_ {
+		namespaces[i] = namespace.Name
+	}
+	return namespaces
+}
+
+// SupportsNamespace returns true if the application supports a namespace,
+// or if the application does not report the namespaces it supports
+func (this *application) SupportsNamespace(ns string) bool {
+	if len(this.Namespaces_) == 0 {
+		return true
+	}
+	for _, namespace := range this.Namespaces_ {
+		if namespace.Name == ns {
+			return true
+		}
+	}
+	return false
+}
+
 func (this *application) Equals(other *application) bool {
 	if this.AppId != other.AppId {
 		return false
 	}
+	if this.AppType != other.AppType {
+		return false
+	}
+	if this.UniversalAppId != other.UniversalAppId {
+		return false
+	}
 	if this.DisplayName != other.DisplayName {
 		return false
 	}
 	if this.IsIdleScreen != other.IsIdleScreen {
 		return false
 	}
-	if this.SessionId != other.SessionId {
+	if this.SessionId_ != other.SessionId_ {
 		return false
 	}
 	if this.StatusText != other.StatusText {
@@ -59,6 +128,14 @@ func (this *application) Equals(other *application) bool {
 	if this.TransportId != other.TransportId {
 		return false
 	}
+	if len(this.Namespaces_) != len(other.Namespaces_) {
+		return false
+	}
+	for i := range this.Namespaces_ {
+		if this.Namespaces_[i] != other.Namespaces_[i] {
+			return false
+		}
+	}
 	return true
 }
 
@@ -66,6 +143,6 @@ func (this *application) Equals(other *application) bool {
 // STRINGIFY
 
 func (this *application) String() string {
-	return fmt.Sprintf("<googlecast.Application>{ id=%v name=%v status=%v session=%v transport=%v idle_screen=%v }",
-		strconv.Quote(this.AppId), strconv.Quote(this.DisplayName), strconv.Quote(this.StatusText), strconv.Quote(this.SessionId), strconv.Quote(this.TransportId), this.IsIdleScreen)
+	return fmt.Sprintf("<googlecast.Application>{ id=%v name=%v type=%v status=%v session=%v transport=%v idle_screen=%v }",
+		strconv.Quote(this.AppId), strconv.Quote(this.DisplayName), strconv.Quote(this.AppType), strconv.Quote(this.StatusText), strconv.Quote(this.SessionId_), strconv.Quote(this.TransportId), this.IsIdleScreen)
 }
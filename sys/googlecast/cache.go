@@ -0,0 +1,237 @@
+/*
+  Go Language Raspberry Pi Interface
+  (c) Copyright David Thorpe 2019
+  All Rights Reserved
+  Documentation http://djthorpe.github.io/gopi/
+  For Licensing and Usage information, please see LICENSE.md
+*/
+
+package googlecast
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	// Frameworks
+	gopi "github.com/djthorpe/gopi"
+	event "github.com/djthorpe/gopi/util/event"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// cache is the on-disk representation of discovered devices
+type cache struct {
+	Devices []cacherecord `json:"devices"`
+}
+
+type cacherecord struct {
+	Record    servicerecord `json:"record"`
+	FirstSeen time.Time     `json:"first_seen"`
+	LastSeen  time.Time     `json:"last_seen"`
+}
+
+// servicerecord implements gopi.RPCServiceRecord for cached records
+type servicerecord struct {
+	Name_    string        `json:"name"`
+	Subtype_ string        `json:"subtype,omitempty"`
+	Service_ string        `json:"service"`
+	Port_    uint          `json:"port"`
+	Text_    []string      `json:"txt,omitempty"`
+	Host_    string        `json:"host"`
+	IP4_     []net.IP      `json:"ip4,omitempty"`
+	IP6_     []net.IP      `json:"ip6,omitempty"`
+	TTL_     time.Duration `json:"ttl"`
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// CONSTANTS
+
+const (
+	CACHE_FILENAME_DEFAULT = "googlecast.json"
+	DELTA_CACHE_WRITE      = 5 * time.Second
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// SERVICE RECORD IMPLEMENTATION
+
+func NewServiceRecord(record gopi.RPCServiceRecord) servicerecord {
+	return servicerecord{
+		Name_:    record.Name(),
+		Subtype_: record.Subtype(),
+		Service_: record.Service(),
+		Port_:    record.Port(),
+		Text_:    record.Text(),
+		Host_:    record.Host(),
+		IP4_:     record.IP4(),
+		IP6_:     record.IP6(),
+		TTL_:     record.TTL(),
+	}
+}
+
+func (this *servicerecord) Name() string    { return this.Name_ }
+func (this *servicerecord) Subtype() string { return this.Subtype_ }
+func (this *servicerecord) Service() string { return this.Service_ }
+func (this *servicerecord) Port() uint      { return this.Port_ }
+func (this *servicerecord) Text() []string  { return this.Text_ }
+func (this *servicerecord) Host() string    { return this.Host_ }
+func (this *servicerecord) IP4() []net.IP   { return this.IP4_ }
+func (this *servicerecord) IP6() []net.IP   { return this.IP6_ }
+
+func (this *servicerecord) TTL() time.Duration {
+	return this.TTL_
+}
+
+func (this *servicerecord) String() string {
+	return fmt.Sprintf("<googlecast.ServiceRecord>{ name=%v service=%v host=%v port=%v }", strconv.Quote(this.Name_), strconv.Quote(this.Service_), strconv.Quote(this.Host_), this.Port_)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// READ AND WRITE CACHE
+
+// cachePath returns the absolute path to the cache file, with the
+// default filename appended if the path is a directory
+func cachePath(path string) (string, error) {
+	if path_, err := filepath.Abs(path); err != nil {
+		return "", err
+	} else {
+		path = path_
+	}
+	if stat, err := os.Stat(path); err == nil && stat.IsDir() {
+		path = filepath.Join(path, CACHE_FILENAME_DEFAULT)
+	}
+	return path, nil
+}
+
+// readCache reads devices from the cache file. The devices are marked as
+// unverified until they are seen again on the network
+func (this *cast) readCache() error {
+	var data cache
+
+	if fh, err := os.Open(this.path); os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	} else {
+		defer fh.Close()
+		if err := json.NewDecoder(fh).Decode(&data); err != nil {
+			return fmt.Errorf("%v: %w", this.path, err)
+		}
+	}
+
+	this.Lock()
+	defer this.Unlock()
+	for _, entry := range data.Devices {
+		record := entry.Record
+		if record.Service() != SERVICE_TYPE_GOOGLECAST {
+			continue
+		} else if device := NewDevice(&record); device.Id() == "" {
+			continue
+		} else if this.allowed(device) == false {
+			continue
+		} else {
+			device.firstSeen = entry.FirstSeen
+			device.lastSeen = entry.LastSeen
+			device.loaded = time.Now()
+			this.devices[device.Id()] = device
+			if device.isGroup() {
+				this.groups[device.Id()] = &castgroup{device, this}
+			}
+		}
+	}
+
+	// Success
+	return nil
+}
+
+// writeCache writes all devices to the cache file
+func (this *cast) writeCache() error {
+	this.Lock()
+	data := cache{Devices: make([]cacherecord, 0, len(this.devices))}
+	for _, device := range this.devices {
+		data.Devices = append(data.Devices, cacherecord{
+			Record:    device.record(),
+			FirstSeen: device.FirstSeen(),
+			LastSeen:  device.LastSeen(),
+		})
+	}
+	this.modified = false
+	this.Unlock()
+
+	// Write to a temporary file which replaces the cache file, so that
+	// the cache is not truncated if writing fails
+	fh, err := ioutil.TempFile(filepath.Dir(this.path), filepath.Base(this.path)+".*")
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(fh)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(data); err != nil {
+		fh.Close()
+		os.Remove(fh.Name())
+		return err
+	} else if err := fh.Close(); err != nil {
+		os.Remove(fh.Name())
+		return err
+	} else if err := os.Rename(fh.Name(), this.path); err != nil {
+		os.Remove(fh.Name())
+		return err
+	}
+
+	// Success
+	return nil
+}
+
+// isModified returns true if the cache requires writing
+func (this *cast) isModified() bool {
+	this.Lock()
+	defer this.Unlock()
+	return this.modified
+}
+
+// setModified marks the cache as requiring writing
+func (this *cast) setModified() {
+	this.Lock()
+	defer this.Unlock()
+	this.modified = true
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// BACKGROUND TASKS
+
+func (this *cast) WriteCache(start chan<- event.Signal, stop <-chan event.Signal) error {
+	this.log.Debug("<googlecast.WriteCache> Started")
+	start <- gopi.DONE
+
+	ticker := time.NewTicker(DELTA_CACHE_WRITE)
+FOR_LOOP:
+	for {
+		select {
+		case <-ticker.C:
+			if this.path == "" || this.isModified() == false {
+				// Do nothing
+			} else if err := this.writeCache(); err != nil {
+				this.log.Warn("WriteCache: %v", err)
+			}
+		case <-stop:
+			ticker.Stop()
+			break FOR_LOOP
+		}
+	}
+
+	// Write cache on exit
+	if this.path != "" {
+		if err := this.writeCache(); err != nil {
+			this.log.Warn("WriteCache: %v", err)
+		}
+	}
+
+	this.log.Debug("<googlecast.WriteCache> Stopped")
+	return nil
+}
@@ -0,0 +1,116 @@
+package googlecast
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	// Frameworks
+	googlecast "github.com/djthorpe/googlecast"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// CACHE
+
+func TestCachePath_000(t *testing.T) {
+	dir, err := ioutil.TempDir("", "googlecast")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		path     string
+		expected string
+	}{
+		{"cache.json", filepath.Join(wd, "cache.json")},
+		{filepath.Join("state", "cache.json"), filepath.Join(wd, "state", "cache.json")},
+		{filepath.Join(dir, "cache.json"), filepath.Join(dir, "cache.json")},
+		{dir, filepath.Join(dir, CACHE_FILENAME_DEFAULT)},
+	}
+	for _, test := range tests {
+		if path, err := cachePath(test.path); err != nil {
+			t.Error(err)
+		} else if path != test.expected {
+			t.Error("Unexpected path", path, "expected", test.expected)
+		}
+	}
+}
+
+func TestCache_000(t *testing.T) {
+	// Devices loaded from the cache are not returned until they are seen
+	dir, err := ioutil.TempDir("", "googlecast")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	record := &servicerecord{
+		Service_: SERVICE_TYPE_GOOGLECAST,
+		Port_:    8009,
+		Text_:    []string{"id=a1", "fn=Kitchen speaker"},
+		IP4_:     []net.IP{net.IPv4(192, 168, 1, 20)},
+	}
+	writer := testCast(t)
+	writer.path = filepath.Join(dir, CACHE_FILENAME_DEFAULT)
+	writer.serviceFound(record)
+	if err := writer.writeCache(); err != nil {
+		t.Fatal(err)
+	}
+
+	this := testCast(t)
+	this.path = writer.path
+	if err := this.readCache(); err != nil {
+		t.Fatal(err)
+	} else if device := this.device("a1"); device == nil {
+		t.Fatal("Expected device to be loaded")
+	} else if device.Verified() {
+		t.Error("Expected device to be unverified")
+	}
+	if devices := this.Devices(); len(devices) != 0 {
+		t.Error("Unexpected devices", devices)
+	} else if device := this.DeviceById("a1"); device != nil {
+		t.Error("Unexpected device", device)
+	} else if devices := this.Query(googlecast.DeviceQuery{Name: "Kitchen speaker"}); len(devices) != 0 {
+		t.Error("Unexpected devices", devices)
+	}
+
+	// The device is added when it is seen on the network
+	C := this.SubscribeEvents(googlecast.SubscribeOptions{})
+	defer this.UnsubscribeEvents(C)
+	this.serviceFound(record)
+	if evt := subscribeNext(t, C); evt.Type() != googlecast.CAST_EVENT_DEVICE_ADDED || evt.Device().Id() != "a1" {
+		t.Error("Unexpected event", evt)
+	} else if devices := this.Devices(); len(devices) != 1 {
+		t.Error("Unexpected devices", devices)
+	} else if devices[0].Verified() == false {
+		t.Error("Expected device to be verified")
+	}
+}
+
+func TestCache_001(t *testing.T) {
+	// Devices loaded from the cache are deleted without an event when expired
+	this := testCast(t)
+	device := NewDevice(&servicerecord{Service_: SERVICE_TYPE_GOOGLECAST, Text_: []string{"id=a1"}})
+	device.loaded = time.Now()
+	this.addDevice(device)
+
+	C := this.SubscribeEvents(googlecast.SubscribeOptions{})
+	defer this.UnsubscribeEvents(C)
+	this.expire(time.Now())
+	if this.device("a1") != nil {
+		t.Error("Expected device to be deleted")
+	}
+	select {
+	case evt := <-C:
+		t.Error("Unexpected event", evt)
+	default:
+	}
+}
@@ -11,6 +11,9 @@ package googlecast
 import (
 	"context"
 	"fmt"
+	"net"
+	"sort"
+	"strconv"
 	"sync"
 	"time"
 
@@ -26,15 +29,58 @@ import (
 
 type Cast struct {
 	Discovery gopi.RPCServiceDiscovery
+
+	// Interval between lookups and timeout for each lookup, or
+	// zero to use the default values
+	LookupInterval time.Duration
+	LookupTimeout  time.Duration
+
+	// Period after which a device which has not been seen and is
+	// not reachable is removed, which is at least one second and
+	// longer than the lookup interval, or zero to use five lookup
+	// intervals
+	Expiry time.Duration
+
+	// Path to the file or directory used to cache discovered devices
+	// across restarts, relative to the working directory, or empty to
+	// disable the cache
+	Path string
+
+	// Devices are discovered when they match any allow rule (or there
+	// are no allow rules) and do not match any deny rule
+	Allow []Rule
+	Deny  []Rule
+
+	// Enable device management such as reboot and factory reset
+	Manage bool
+
+	// Number of events retained for replay, or zero to use the
+	// default value
+	History int
+
+	// Period over which state events for each device are merged into
+	// a single event with the final state, or zero to disable
+	Coalesce time.Duration
 }
 
 type cast struct {
 	log       gopi.Logger
 	discovery gopi.RPCServiceDiscovery
+	interval  time.Duration
+	timeout   time.Duration
+	expiry    time.Duration
+	path      string
+	modified  bool
+	allow     []Rule
+	deny      []Rule
+	manage    bool
+	coalesce  time.Duration
 	devices   map[string]*castdevice
+	groups    map[string]*castgroup
 	channels  map[*castchannel]*castdevice
+	lookup    sync.Mutex
+	subs      subscribers
 
-	event.Publisher
 	event.Tasks
 	sync.Mutex
 	sync.WaitGroup
@@ -46,26 +92,74 @@ type cast struct {
 const (
 	SERVICE_TYPE_GOOGLECAST = "_googlecast._tcp"
 	DELTA_LOOKUP_TIME       = 60 * time.Second
+	DELTA_LOOKUP_TIMEOUT    = 2 * time.Second
+	DELTA_INTERFACE_TIME    = 5 * time.Second
+	DELTA_EXPIRY_LOOKUPS    = 5
+	MIN_EXPIRY_TIME         = time.Second
+	DEFAULT_EVENT_BUFFER    = 100
+	DEFAULT_EVENT_HISTORY   = 1000
 )
 
 ////////////////////////////////////////////////////////////////////////////////
 // OPEN AND CLOSE
 
 func (config Cast) Open(logger gopi.Logger) (gopi.Driver, error) {
-	logger.Debug("<googlecast.Open>{ discovery=%v }", config.Discovery)
+	logger.Debug("<googlecast.Open>{ discovery=%v lookup_interval=%v lookup_timeout=%v expiry=%v path=%v allow=%v deny=%v manage=%v history=%v coalesce=%v }", config.Discovery, config.LookupInterval, config.LookupTimeout, config.Expiry, strconv.Quote(config.Path), config.Allow, config.Deny, config.Manage, config.History, config.Coalesce)
 
 	this := new(cast)
 	this.log = logger
 	this.discovery = config.Discovery
+	this.interval = config.LookupInterval
+	this.timeout = config.LookupTimeout
+	this.expiry = config.Expiry
+	this.allow = config.Allow
+	this.deny = config.Deny
+	this.manage = config.Manage
+	this.coalesce = config.Coalesce
 	this.devices = make(map[string]*castdevice)
+	this.groups = make(map[string]*castgroup)
 	this.channels = make(map[*castchannel]*castdevice)
+	this.subs.size = config.History
 
 	if this.discovery == nil {
 		return nil, gopi.ErrBadParameter
 	}
+	if this.interval == 0 {
+		this.interval = DELTA_LOOKUP_TIME
+	}
+	if this.timeout == 0 {
+		this.timeout = DELTA_LOOKUP_TIMEOUT
+	}
+	if this.expiry == 0 {
+		this.expiry = DELTA_EXPIRY_LOOKUPS * this.interval
+	}
+	if this.subs.size == 0 {
+		this.subs.size = DEFAULT_EVENT_HISTORY
+	}
+	if this.interval < 0 || this.timeout < 0 || this.expiry < 0 || this.subs.size < 0 || this.coalesce < 0 {
+		return nil, gopi.ErrBadParameter
+	}
+	if this.expiry < MIN_EXPIRY_TIME {
+		return nil, fmt.Errorf("%w: Expiry should be at least %v", gopi.ErrBadParameter, MIN_EXPIRY_TIME)
+	}
+	if this.expiry <= this.interval {
+		return nil, fmt.Errorf("%w: Expiry should be longer than the lookup interval %v", gopi.ErrBadParameter, this.interval)
+	}
+
+	// Load devices from the cache
+	if config.Path != "" {
+		if path, err := cachePath(config.Path); err != nil {
+			return nil, err
+		} else {
+			this.path = path
+		}
+		if err := this.readCache(); err != nil {
+			return nil, err
+		}
+	}
 
 	// Run background tasks
-	this.Tasks.Start(this.Watch, this.Lookup)
+	this.Tasks.Start(this.Watch, this.Lookup, this.WatchInterfaces, this.Expire, this.WriteCache)
 
 	// Success
 	return this, nil
@@ -90,11 +184,12 @@ func (this *cast) Close() error {
 	}
 
 	// Unsubscribe
-	this.Publisher.Close()
+	this.subs.Close()
 
 	// Release resources
 	this.channels = nil
 	this.devices = nil
+	this.groups = nil
 
 	// Return any errors caught
 	return errs.ErrorOrSelf()
@@ -120,15 +215,111 @@ func (this *cast) Devices() []googlecast.Device {
 
 	devices := make([]googlecast.Device, 0, len(this.devices))
 	for _, device := range this.devices {
-		devices = append(devices, device)
+		if device.Verified() {
+			devices = append(devices, this.wrap(device))
+		}
 	}
 	return devices
 }
 
+func (this *cast) DeviceById(id string) googlecast.Device {
+	this.Lock()
+	defer this.Unlock()
+
+	if device, exists := this.devices[id]; exists && device.Verified() {
+		return this.wrap(device)
+	} else {
+		return nil
+	}
+}
+
+func (this *cast) DevicesByName(name string) []googlecast.Device {
+	return this.Query(googlecast.DeviceQuery{Name: name})
+}
+
+func (this *cast) DevicesByModel(model string) []googlecast.Device {
+	return this.Query(googlecast.DeviceQuery{Model: model})
+}
+
+func (this *cast) DevicesByCapability(capabilities googlecast.DeviceCapability) []googlecast.Device {
+	return this.Query(googlecast.DeviceQuery{Capabilities: capabilities})
+}
+
+func (this *cast) Query(query googlecast.DeviceQuery) []googlecast.Device {
+	this.Lock()
+	defer this.Unlock()
+
+	devices := make([]googlecast.Device, 0, len(this.devices))
+	for _, device := range this.devices {
+		if device.Verified() && device.Matches(query) {
+			devices = append(devices, this.wrap(device))
+		}
+	}
+	return devices
+}
+
+func (this *cast) WaitForDevice(ctx context.Context, query googlecast.DeviceQuery) (googlecast.Device, error) {
+	this.log.Debug2("<googlecast.WaitForDevice>{ query=%+v }", query)
+
+	// Subscribe before checking existing devices so that none are missed
+	events := this.SubscribeEvents(googlecast.SubscribeOptions{
+		Types: []googlecast.EventType{googlecast.CAST_EVENT_DEVICE_ADDED, googlecast.CAST_EVENT_DEVICE_UPDATED},
+	})
+	defer this.UnsubscribeEvents(events)
+
+	// Return any existing device
+	if devices := this.Query(query); len(devices) > 0 {
+		return devices[0], nil
+	}
+
+	// Wait for device to be added or updated
+	for {
+		select {
+		case evt := <-events:
+			if evt == nil {
+				// Channel closed when cast is closed
+				return nil, gopi.ErrOutOfOrder
+			} else if device := unwrap(evt.Device()); device != nil && device.Matches(query) {
+				return evt.Device(), nil
+			}
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// Refresh performs an immediate lookup of devices, and returns when the
+// lookup has completed. If the context has no deadline then the lookup
+// timeout is applied
+func (this *cast) Refresh(ctx context.Context) error {
+	this.log.Debug2("<googlecast.Refresh>{ }")
+
+	// Only one lookup can be performed at a time
+	this.lookup.Lock()
+	defer this.lookup.Unlock()
+
+	if _, exists := ctx.Deadline(); exists == false {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, this.timeout)
+		defer cancel()
+	}
+
+	if records, err := this.discovery.Lookup(ctx, SERVICE_TYPE_GOOGLECAST); err != nil {
+		return err
+	} else {
+		for _, record := range records {
+			this.serviceFound(record)
+		}
+	}
+
+	// Success
+	return nil
+}
+
 func (this *cast) Connect(device googlecast.Device, flag gopi.RPCFlag, timeout time.Duration) (googlecast.Channel, error) {
 	this.log.Debug2("<googlecast.Connect>{ device=%v flag=%v timeout=%v }", device, flag, timeout)
 
-	if device_, ok := device.(*castdevice); device_ == nil || ok == false {
+	if device_ := unwrap(device); device_ == nil {
 		return nil, gopi.ErrBadParameter
 	} else if ip, err := device_.addr(flag); err != nil {
 		return nil, err
@@ -146,6 +337,13 @@ func (this *cast) Connect(device googlecast.Device, flag gopi.RPCFlag, timeout t
 		// Watch channel for messages
 		go this.WatchChannelEvents(device, channel_.Subscribe())
 
+		// Request membership for groups
+		if device_.isGroup() {
+			if _, err := channel_.GetMultizoneStatus(); err != nil {
+				this.log.Warn("GetMultizoneStatus: %v", err)
+			}
+		}
+
 		// Return success
 		return channel_, nil
 	}
@@ -176,11 +374,12 @@ FOR_LOOP:
 	for {
 		select {
 		case <-timer.C:
-			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
-			this.discovery.Lookup(ctx, SERVICE_TYPE_GOOGLECAST)
-			cancel()
-			timer.Reset(DELTA_LOOKUP_TIME)
+			if err := this.Refresh(context.Background()); err != nil {
+				this.log.Warn("Lookup: %v", err)
+			}
+			timer.Reset(this.interval)
 		case <-stop:
+			timer.Stop()
 			break FOR_LOOP
 		}
 	}
@@ -188,6 +387,33 @@ FOR_LOOP:
 	return nil
 }
 
+func (this *cast) WatchInterfaces(start chan<- event.Signal, stop <-chan event.Signal) error {
+	this.log.Debug("<googlecast.WatchInterfaces> Started")
+	start <- gopi.DONE
+
+	// Re-query devices when network interfaces or addresses change
+	addrs := interfaceAddrs()
+	ticker := time.NewTicker(DELTA_INTERFACE_TIME)
+FOR_LOOP:
+	for {
+		select {
+		case <-ticker.C:
+			if addrs_ := interfaceAddrs(); addrs_ != addrs {
+				this.log.Debug("<googlecast.WatchInterfaces> Network interfaces changed")
+				addrs = addrs_
+				if err := this.Refresh(context.Background()); err != nil {
+					this.log.Warn("WatchInterfaces: %v", err)
+				}
+			}
+		case <-stop:
+			ticker.Stop()
+			break FOR_LOOP
+		}
+	}
+	this.log.Debug("<googlecast.WatchInterfaces> Stopped")
+	return nil
+}
+
 func (this *cast) Watch(start chan<- event.Signal, stop <-chan event.Signal) error {
 	this.log.Debug("<googlecast.Watch> Started")
 	start <- gopi.DONE
@@ -209,14 +435,76 @@ FOR_LOOP:
 	return nil
 }
 
+func (this *cast) Expire(start chan<- event.Signal, stop <-chan event.Signal) error {
+	this.log.Debug("<googlecast.Expire> Started")
+	start <- gopi.DONE
+
+	// Remove devices which have not been seen within the expiry period
+	ticker := time.NewTicker(this.expiry / 4)
+FOR_LOOP:
+	for {
+		select {
+		case <-ticker.C:
+			this.expire(time.Now().Add(-this.expiry))
+		case <-stop:
+			ticker.Stop()
+			break FOR_LOOP
+		}
+	}
+	this.log.Debug("<googlecast.Expire> Stopped")
+	return nil
+}
+
+// expire disconnects and deletes devices which have not been seen
+// since the time provided. Channels are disconnected before the
+// device is deleted, so that subscribers which disconnect channels
+// on deletion find them already disconnected
+func (this *cast) expire(since time.Time) {
+	for _, device := range this.expiredDevices(since) {
+		this.log.Debug("<googlecast.Expire> Expired: %v", device)
+		for _, channel := range this.channelsForDevice(device) {
+			if err := this.Disconnect(channel); err != nil {
+				this.log.Warn("Expire: %v", err)
+			}
+		}
+		if device.Verified() {
+			this.emitDevice(googlecast.CAST_EVENT_DEVICE_DELETED, device)
+		}
+		this.deleteDevice(device)
+	}
+}
+
 func (this *cast) WatchChannelEvents(device googlecast.Device, evts <-chan gopi.Event) {
 	this.WaitGroup.Add(1)
+
+	// State events are merged over the coalescing period, and the
+	// timer is stopped whenever coalesced events are flushed
+	coalesced := make([]*castevent, 0)
+	var timer *time.Timer
+	flush := func() {
+		coalesced = this.flush(coalesced)
+		if timer != nil {
+			timer.Stop()
+			timer = nil
+		}
+	}
 FOR_LOOP:
 	for {
+		var expired <-chan time.Time
+		if timer != nil {
+			expired = timer.C
+		}
 		select {
 		case evt := <-evts:
 			if evt == nil {
-				continue
+				break FOR_LOOP
+			} else if evt_, ok := evt.(*castgroupevent); ok {
+				// Append group and resolve members
+				flush()
+				evt_.device_ = device
+				evt_.source_ = this
+				evt_.members_ = this.devicesForIds(evt_.ids_)
+				this.emit(evt_)
 			} else if evt_, ok := evt.(*castevent); ok == false {
 				continue
 			} else if evt_.Type() == googlecast.CAST_EVENT_CHANNEL_DISCONNECT {
@@ -225,51 +513,226 @@ FOR_LOOP:
 				// Append device
 				evt_.device_ = device
 				evt_.source_ = this
-				this.Emit(evt_)
+				if this.coalesce == 0 || isStateEvent(evt_.type_) == false {
+					flush()
+					this.emit(evt_)
+				} else {
+					coalesced = coalesce(coalesced, evt_)
+					if timer == nil {
+						timer = time.NewTimer(this.coalesce)
+					}
+				}
 			}
+		case <-expired:
+			timer = nil
+			flush()
 		}
 	}
+	flush()
 	this.WaitGroup.Done()
 }
 
+// coalesce merges an event with an earlier event of the same type,
+// retaining the earlier previous state and the later state
+func coalesce(events []*castevent, evt *castevent) []*castevent {
+	for _, other := range events {
+		if other.type_ == evt.type_ {
+			other.state_ = evt.state_
+			other.reqid_ = evt.reqid_
+			return events
+		}
+	}
+	return append(events, evt)
+}
+
+// flush emits coalesced events and returns an empty set of events
+func (this *cast) flush(events []*castevent) []*castevent {
+	for _, evt := range events {
+		this.emit(evt)
+	}
+	return events[:0]
+}
+
+// isStateEvent returns true for events which report changes to
+// channel state and which can be coalesced
+func isStateEvent(type_ googlecast.EventType) bool {
+	switch type_ {
+	case googlecast.CAST_EVENT_VOLUME_UPDATED, googlecast.CAST_EVENT_APPLICATION_UPDATED, googlecast.CAST_EVENT_MEDIA_UPDATED:
+		return true
+	case googlecast.CAST_EVENT_INPUT_UPDATED, googlecast.CAST_EVENT_STANDBY_UPDATED:
+		return true
+	case googlecast.CAST_EVENT_MEDIA_PLAYER_STATE_UPDATED, googlecast.CAST_EVENT_MEDIA_CONTENT_UPDATED, googlecast.CAST_EVENT_MEDIA_QUEUE_UPDATED:
+		return true
+	case googlecast.CAST_EVENT_MEDIA_TRACKS_UPDATED, googlecast.CAST_EVENT_MEDIA_POSITION_JUMPED:
+		return true
+	default:
+		return false
+	}
+}
+
 ////////////////////////////////////////////////////////////////////////////////
 // PRIVATE METHODS
 
 func (this *cast) WatchEvent(evt gopi.RPCEvent) error {
 	if service := evt.ServiceRecord(); service == nil || service.Service() != SERVICE_TYPE_GOOGLECAST {
 		return nil
-	} else if device := NewDevice(service); device.Id() == "" {
-		return nil
 	} else if evt.Type() == gopi.RPC_EVENT_SERVICE_EXPIRED {
-		this.Emit(&castevent{googlecast.CAST_EVENT_DEVICE_DELETED, this, device, nil, 0})
-		this.deleteDevice(device)
+		this.serviceExpired(service)
 	} else if evt.Type() == gopi.RPC_EVENT_SERVICE_ADDED || evt.Type() == gopi.RPC_EVENT_SERVICE_UPDATED {
-		if device_, exists := this.devices[device.Id()]; device_ == nil || exists == false {
-			this.addDevice(device)
-			this.Emit(&castevent{googlecast.CAST_EVENT_DEVICE_ADDED, this, device, nil, 0})
-		} else if device.Equals(device_) == false {
-			this.addDevice(device)
-			this.Emit(&castevent{googlecast.CAST_EVENT_DEVICE_UPDATED, this, device, nil, 0})
-		}
+		this.serviceFound(service)
 	}
 	// Success
 	return nil
 }
 
+func (this *cast) serviceFound(service gopi.RPCServiceRecord) {
+	if device := NewDevice(service); device.Id() == "" {
+		return
+	} else if this.allowed(device) == false {
+		// Remove any existing device which is no longer allowed
+		if device_ := this.device(device.Id()); device_ != nil {
+			if device_.Verified() {
+				this.emitDevice(googlecast.CAST_EVENT_DEVICE_DELETED, device_)
+			}
+			this.deleteDevice(device_)
+		}
+	} else if device_ := this.device(device.Id()); device_ == nil {
+		device.seen(time.Now())
+		this.addDevice(device)
+		this.emitDevice(googlecast.CAST_EVENT_DEVICE_ADDED, device)
+	} else if device_.Verified() == false {
+		// A device loaded from the cache is added when it is first
+		// seen on the network, so that it can be connected
+		device_.setRecord(service)
+		device_.seen(time.Now())
+		this.setModified()
+		this.emitDevice(googlecast.CAST_EVENT_DEVICE_ADDED, device_)
+	} else if device.Equals(device_) == false {
+		// Update the existing device so that the first seen time and
+		// any connected channel is retained
+		device_.setRecord(service)
+		device_.seen(time.Now())
+		this.setModified()
+		this.emitDevice(googlecast.CAST_EVENT_DEVICE_UPDATED, device_)
+	} else {
+		device_.seen(time.Now())
+	}
+}
+
+func (this *cast) serviceExpired(service gopi.RPCServiceRecord) {
+	if device := NewDevice(service); device.Id() == "" {
+		return
+	} else if device_ := this.device(device.Id()); device_ != nil {
+		if device_.Verified() {
+			this.emitDevice(googlecast.CAST_EVENT_DEVICE_DELETED, device_)
+		}
+		this.deleteDevice(device_)
+	}
+}
+
 func NewDevice(srv gopi.RPCServiceRecord) *castdevice {
 	return &castdevice{RPCServiceRecord: srv}
 }
 
+func (this *cast) device(id string) *castdevice {
+	this.Lock()
+	defer this.Unlock()
+	if device, exists := this.devices[id]; exists {
+		return device
+	} else {
+		return nil
+	}
+}
+
 func (this *cast) addDevice(device *castdevice) {
 	this.Lock()
 	defer this.Unlock()
 	this.devices[device.Id()] = device
+	if device.isGroup() {
+		this.groups[device.Id()] = &castgroup{device, this}
+	}
+	this.modified = true
 }
 
 func (this *cast) deleteDevice(device *castdevice) {
 	this.Lock()
 	defer this.Unlock()
 	delete(this.devices, device.Id())
+	delete(this.groups, device.Id())
+	this.modified = true
+}
+
+// deviceFor returns the group for a device if it is a group, or
+// the device otherwise
+func (this *cast) deviceFor(device *castdevice) googlecast.Device {
+	this.Lock()
+	defer this.Unlock()
+	return this.wrap(device)
+}
+
+// wrap returns the group for a device if it is a group, and should
+// be called with the lock held
+func (this *cast) wrap(device *castdevice) googlecast.Device {
+	if group, exists := this.groups[device.Id()]; exists {
+		return group
+	} else {
+		return device
+	}
+}
+
+// devicesForIds returns discovered devices for a set of identifiers
+func (this *cast) devicesForIds(ids []string) []googlecast.Device {
+	this.Lock()
+	defer this.Unlock()
+	devices := make([]googlecast.Device, 0, len(ids))
+	for _, id := range ids {
+		if device, exists := this.devices[id]; exists && device.Verified() {
+			devices = append(devices, this.wrap(device))
+		}
+	}
+	return devices
+}
+
+// devicesForAddr returns discovered devices other than groups which
+// share an address with a device, in order of identifier
+func (this *cast) devicesForAddr(device *castdevice) []*castdevice {
+	this.Lock()
+	defer this.Unlock()
+	devices := make([]*castdevice, 0)
+	for _, other := range this.devices {
+		if other != device && other.Verified() && other.isGroup() == false && other.sameAddr(device) {
+			devices = append(devices, other)
+		}
+	}
+	sort.Slice(devices, func(i, j int) bool {
+		return devices[i].Id() < devices[j].Id()
+	})
+	return devices
+}
+
+// unwrap returns the underlying device for a device or group, or
+// nil if the device was not discovered by this driver
+func unwrap(device googlecast.Device) *castdevice {
+	switch device_ := device.(type) {
+	case *castdevice:
+		return device_
+	case *castgroup:
+		return device_.castdevice
+	default:
+		return nil
+	}
+}
+
+func (this *cast) expiredDevices(since time.Time) []*castdevice {
+	this.Lock()
+	defer this.Unlock()
+	devices := make([]*castdevice, 0)
+	for _, device := range this.devices {
+		if device.expired(since) {
+			devices = append(devices, device)
+		}
+	}
+	return devices
 }
 
 func (this *cast) addChannel(device *castdevice, channel *castchannel) error {
@@ -279,17 +742,48 @@ func (this *cast) addChannel(device *castdevice, channel *castchannel) error {
 		return gopi.ErrNotFound
 	} else {
 		this.channels[channel] = device
+		device.setChannel(channel)
 		return nil
 	}
 }
 
+// channelsForDevice returns the channels connected to a device
+func (this *cast) channelsForDevice(device *castdevice) []*castchannel {
+	this.Lock()
+	defer this.Unlock()
+	channels := make([]*castchannel, 0)
+	for channel, device_ := range this.channels {
+		if device_ == device {
+			channels = append(channels, channel)
+		}
+	}
+	return channels
+}
+
 func (this *cast) deleteChannel(channel *castchannel) error {
 	this.Lock()
 	defer this.Unlock()
-	if _, exists := this.channels[channel]; exists {
+	if device, exists := this.channels[channel]; exists {
 		delete(this.channels, channel)
+		device.setChannel(nil)
 		return nil
 	} else {
 		return gopi.ErrNotFound
 	}
 }
+
+// interfaceAddrs returns the addresses of all network interfaces which are
+// up, as a string which can be compared to detect changes
+func interfaceAddrs() string {
+	str := ""
+	if ifaces, err := net.Interfaces(); err == nil {
+		for _, iface := range ifaces {
+			if iface.Flags&net.FlagUp == 0 {
+				continue
+			} else if addrs, err := iface.Addrs(); err == nil {
+				str += fmt.Sprintf("%v%v ", iface.Name, addrs)
+			}
+		}
+	}
+	return str
+}
@@ -0,0 +1,221 @@
+package googlecast
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	// Frameworks
+	googlecast "github.com/djthorpe/googlecast"
+	gopi "github.com/djthorpe/gopi"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// testdiscovery is service discovery which finds no services
+type testdiscovery struct {
+	gopi.RPCServiceDiscovery
+	C chan gopi.Event
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// OPEN
+
+func TestOpen_000(t *testing.T) {
+	// Expiry defaults to five lookup intervals
+	for _, interval := range []time.Duration{0, time.Minute, time.Hour} {
+		if driver, err := gopi.Open(Cast{Discovery: testDiscovery(), LookupInterval: interval}, testLogger(t)); err != nil {
+			t.Error(err)
+		} else {
+			this := driver.(*cast)
+			if this.expiry != DELTA_EXPIRY_LOOKUPS*this.interval {
+				t.Error("Unexpected expiry", this.expiry, "for lookup interval", this.interval)
+			}
+			if err := this.Close(); err != nil {
+				t.Error(err)
+			}
+		}
+	}
+}
+
+func TestOpen_001(t *testing.T) {
+	// Expiry should be at least one second and longer than the lookup interval
+	for _, config := range []Cast{
+		{Expiry: time.Millisecond},
+		{Expiry: DELTA_LOOKUP_TIME},
+		{Expiry: time.Minute, LookupInterval: 2 * time.Minute},
+		{Expiry: -time.Second},
+	} {
+		config.Discovery = testDiscovery()
+		if _, err := gopi.Open(config, testLogger(t)); errors.Is(err, gopi.ErrBadParameter) == false {
+			t.Error("Unexpected error", err, "for", config)
+		}
+	}
+	if driver, err := gopi.Open(Cast{Discovery: testDiscovery(), Expiry: 2 * time.Minute, LookupInterval: time.Minute}, testLogger(t)); err != nil {
+		t.Error(err)
+	} else if err := driver.Close(); err != nil {
+		t.Error(err)
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// COALESCE
+
+func TestCoalesce_000(t *testing.T) {
+	// A flush stops the coalescing timer, so state events after the
+	// flush are merged over a full coalescing period
+	this := &cast{coalesce: 200 * time.Millisecond}
+	C := this.subs.Subscribe(googlecast.SubscribeOptions{}, nil)
+	defer this.subs.Unsubscribe(C)
+
+	evts := make(chan gopi.Event)
+	go this.WatchChannelEvents(nil, evts)
+
+	evts <- &castevent{type_: googlecast.CAST_EVENT_VOLUME_UPDATED}
+	evts <- &castevent{type_: googlecast.CAST_EVENT_CHANNEL_CONNECT}
+	for _, expected := range []googlecast.EventType{googlecast.CAST_EVENT_VOLUME_UPDATED, googlecast.CAST_EVENT_CHANNEL_CONNECT} {
+		if evt := subscribeNext(t, C); evt.Type() != expected {
+			t.Error("Unexpected event", evt.Type(), "expected", expected)
+		}
+	}
+
+	// Send state events either side of the original timer expiry
+	time.Sleep(150 * time.Millisecond)
+	evts <- &castevent{type_: googlecast.CAST_EVENT_MEDIA_UPDATED, reqid_: 1}
+	time.Sleep(100 * time.Millisecond)
+	evts <- &castevent{type_: googlecast.CAST_EVENT_MEDIA_UPDATED, reqid_: 2}
+	if evt := subscribeNext(t, C); evt.Type() != googlecast.CAST_EVENT_MEDIA_UPDATED {
+		t.Error("Unexpected event", evt.Type())
+	} else if evt.(*castevent).reqid_ != 2 {
+		t.Error("Expected coalesced event, got", evt)
+	}
+
+	close(evts)
+	this.WaitGroup.Wait()
+	select {
+	case evt := <-C:
+		t.Error("Unexpected event", evt)
+	default:
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// EXPIRE
+
+func TestExpire_000(t *testing.T) {
+	// A connected device which expires is disconnected and then deleted
+	server := newCastServer(t)
+	defer server.Close()
+
+	this := testCast(t)
+	device := NewDevice(server.Record("a1b2c3", "Kitchen speaker", "Chromecast"))
+	device.seen(time.Now().Add(-time.Hour))
+	this.addDevice(device)
+
+	channel, err := this.Connect(device, gopi.RPC_FLAG_INET_V4, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	C := this.SubscribeEvents(googlecast.SubscribeOptions{Types: []googlecast.EventType{googlecast.CAST_EVENT_DEVICE_DELETED}})
+	defer this.UnsubscribeEvents(C)
+
+	this.expire(time.Now().Add(-time.Minute))
+	if evt := subscribeNext(t, C); evt.Device().Id() != device.Id() {
+		t.Error("Unexpected event", evt)
+	} else if this.device(device.Id()) != nil {
+		t.Error("Expected device to be deleted")
+	} else if channels := this.channelsForDevice(device); len(channels) != 0 {
+		t.Error("Unexpected channels", channels)
+	}
+
+	// The channel is already disconnected when the deleted event is received
+	if err := this.Disconnect(channel); errors.Is(err, gopi.ErrNotFound) == false {
+		t.Error("Unexpected error", err)
+	}
+	this.WaitGroup.Wait()
+}
+
+func TestExpire_001(t *testing.T) {
+	// A device which has been seen recently does not expire
+	this := testCast(t)
+	device := NewDevice(&servicerecord{Service_: SERVICE_TYPE_GOOGLECAST, Text_: []string{"id=a1b2c3"}})
+	device.seen(time.Now())
+	this.addDevice(device)
+	this.expire(time.Now().Add(-time.Minute))
+	if this.device(device.Id()) == nil {
+		t.Error("Unexpected device deletion")
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// WAIT FOR DEVICE
+
+func TestWaitForDevice_000(t *testing.T) {
+	// Returns when the context is cancelled and no device matches
+	this := testCast(t)
+	this.serviceFound(&servicerecord{Service_: SERVICE_TYPE_GOOGLECAST, Text_: []string{"id=a1", "fn=Kitchen speaker"}})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if device, err := this.WaitForDevice(ctx, googlecast.DeviceQuery{Name: "Living*"}); errors.Is(err, context.DeadlineExceeded) == false {
+		t.Error("Unexpected error", err)
+	} else if device != nil {
+		t.Error("Unexpected device", device)
+	}
+}
+
+func TestWaitForDevice_001(t *testing.T) {
+	// Returns an existing device, or a device once it is added
+	this := testCast(t)
+	this.serviceFound(&servicerecord{Service_: SERVICE_TYPE_GOOGLECAST, Text_: []string{"id=a1", "fn=Kitchen speaker"}})
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if device, err := this.WaitForDevice(ctx, googlecast.DeviceQuery{Name: "Kitchen*"}); err != nil {
+		t.Error(err)
+	} else if device.Id() != "a1" {
+		t.Error("Unexpected device", device)
+	}
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		this.serviceFound(&servicerecord{Service_: SERVICE_TYPE_GOOGLECAST, Text_: []string{"id=b2", "fn=Bedroom speaker"}})
+		this.serviceFound(&servicerecord{Service_: SERVICE_TYPE_GOOGLECAST, Text_: []string{"id=c3", "fn=Living room TV"}})
+	}()
+	if device, err := this.WaitForDevice(ctx, googlecast.DeviceQuery{Name: "Living*"}); err != nil {
+		t.Error(err)
+	} else if device.Id() != "c3" {
+		t.Error("Unexpected device", device)
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// UTILS
+
+func testDiscovery() *testdiscovery {
+	return &testdiscovery{C: make(chan gopi.Event)}
+}
+
+func (this *testdiscovery) Lookup(context.Context, string) ([]gopi.RPCServiceRecord, error) {
+	return nil, nil
+}
+
+func (this *testdiscovery) Subscribe() <-chan gopi.Event {
+	return this.C
+}
+
+func (this *testdiscovery) Unsubscribe(<-chan gopi.Event) {
+}
+
+// testCast returns a driver without background tasks
+func testCast(t *testing.T) *cast {
+	t.Helper()
+	return &cast{
+		log:      testLogger(t),
+		devices:  make(map[string]*castdevice),
+		groups:   make(map[string]*castgroup),
+		channels: make(map[*castchannel]*castdevice),
+	}
+}
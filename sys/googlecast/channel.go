@@ -9,6 +9,7 @@
 package googlecast
 
 import (
+	"context"
 	"crypto/tls"
 	"encoding/binary"
 	"encoding/json"
@@ -44,17 +45,30 @@ type castchannel struct {
 	conn      *tls.Conn
 	timeout   time.Duration
 	messageid int
+	received  time.Time
+	pending   map[int]chan string
+	launches  map[int]castlaunch
 
 	// The current status of the device
-	app    *application
-	volume *volume
-	media  *media
+	app     *application
+	volume  *volume
+	media   *media
+	members map[string]multizonedevice
+	input   *bool
+	standby *bool
 
 	sync.Mutex
 	event.Tasks
 	event.Publisher
 }
 
+// castlaunch is a launch of an application from this channel
+// which has not yet been answered
+type castlaunch struct {
+	appId string
+	ts    time.Time
+}
+
 ////////////////////////////////////////////////////////////////////////////////
 // CONSTANTS
 
@@ -62,12 +76,14 @@ const (
 	DEFAULT_TIMEOUT       = 5 * time.Second
 	READ_TIMEOUT          = 500 * time.Millisecond
 	STATUS_INTERVAL       = 10 * time.Second
+	LAUNCH_TIMEOUT        = 30 * time.Second
 	CAST_DEFAULT_SENDER   = "sender-0"
 	CAST_DEFAULT_RECEIVER = "receiver-0"
 	CAST_NS_CONN          = "urn:x-cast:com.google.cast.tp.connection"
 	CAST_NS_HEARTBEAT     = "urn:x-cast:com.google.cast.tp.heartbeat"
 	CAST_NS_RECV          = "urn:x-cast:com.google.cast.receiver"
 	CAST_NS_MEDIA         = "urn:x-cast:com.google.cast.media"
+	CAST_NS_MULTIZONE     = "urn:x-cast:com.google.cast.multizone"
 )
 
 ////////////////////////////////////////////////////////////////////////////////
@@ -78,6 +94,8 @@ func (config Channel) Open(log gopi.Logger) (gopi.Driver, error) {
 
 	this := new(castchannel)
 	this.log = log
+	this.pending = make(map[int]chan string)
+	this.launches = make(map[int]castlaunch)
 	if config.Timeout == 0 {
 		this.timeout = DEFAULT_TIMEOUT
 	} else {
@@ -136,6 +154,9 @@ func (this *castchannel) Close() error {
 	this.app = nil
 	this.volume = nil
 	this.media = nil
+	this.members = nil
+	this.input = nil
+	this.standby = nil
 
 	// Success
 	return nil
@@ -145,7 +166,7 @@ func (this *castchannel) Close() error {
 // STRINGIFY
 
 func (this *castchannel) String() string {
-	return fmt.Sprintf("<googlecast.Channel>{ remote_addr=%v app=%v volume=%v media=%v }", strconv.Quote(this.RemoteAddr()), this.app, this.volume, this.media)
+	return fmt.Sprintf("<googlecast.Channel>{ remote_addr=%v app=%v volume=%v media=%v active_input=%v standby=%v }", strconv.Quote(this.RemoteAddr()), this.app, this.volume, this.media, this.ActiveInput(), this.StandBy())
 }
 
 ////////////////////////////////////////////////////////////////////////////////
@@ -167,6 +188,14 @@ func (this *castchannel) RemoteAddr() string {
 	}
 }
 
+// LastReceived returns the time a message was last received from
+// the device, or zero if no message has been received
+func (this *castchannel) LastReceived() time.Time {
+	this.Lock()
+	defer this.Unlock()
+	return this.received
+}
+
 ////////////////////////////////////////////////////////////////////////////////
 // CONNECT AND DISCONNECT MESSAGES
 
@@ -177,6 +206,9 @@ func (this *castchannel) Connect() error {
 	this.app = nil
 	this.volume = nil
 	this.media = nil
+	this.members = nil
+	this.input = nil
+	this.standby = nil
 
 	// Send CONNECT message
 	payload := &PayloadHeader{Type: "CONNECT"}
@@ -184,9 +216,7 @@ func (this *castchannel) Connect() error {
 	if err := this.send(CAST_DEFAULT_SENDER, CAST_DEFAULT_RECEIVER, CAST_NS_CONN, payload.WithId(reqid)); err != nil {
 		return err
 	} else {
-		this.Emit(&castevent{
-			googlecast.CAST_EVENT_CHANNEL_CONNECT, this, nil, this, reqid,
-		})
+		this.emit(googlecast.CAST_EVENT_CHANNEL_CONNECT, reqid, nil)
 	}
 
 	// Success
@@ -202,15 +232,16 @@ func (this *castchannel) Disconnect() error {
 	if err := this.send(CAST_DEFAULT_SENDER, CAST_DEFAULT_RECEIVER, CAST_NS_CONN, payload.WithId(reqid)); err != nil {
 		return err
 	} else {
-		this.Emit(&castevent{
-			googlecast.CAST_EVENT_CHANNEL_DISCONNECT, this, nil, this, reqid,
-		})
+		this.emit(googlecast.CAST_EVENT_CHANNEL_DISCONNECT, reqid, nil)
 	}
 
 	// Release resources
 	this.app = nil
 	this.volume = nil
 	this.media = nil
+	this.members = nil
+	this.input = nil
+	this.standby = nil
 
 	// Success
 	return nil
@@ -220,15 +251,55 @@ func (this *castchannel) Disconnect() error {
 // RETURN PROPERTIES
 
 func (this *castchannel) Application() googlecast.Application {
-	return this.app
+	if this.app == nil {
+		return nil
+	} else {
+		return this.app
+	}
 }
 
 func (this *castchannel) Volume() googlecast.Volume {
-	return this.volume
+	if this.volume == nil {
+		return nil
+	} else {
+		return this.volume
+	}
 }
 
 func (this *castchannel) Media() googlecast.Media {
-	return this.media
+	if this.media == nil {
+		return nil
+	} else {
+		return this.media
+	}
+}
+
+func (this *castchannel) ActiveInput() bool {
+	if this.input == nil {
+		return false
+	} else {
+		return *this.input
+	}
+}
+
+func (this *castchannel) StandBy() bool {
+	if this.standby == nil {
+		return false
+	} else {
+		return *this.standby
+	}
+}
+
+// MemberIds returns the identifiers of group members when connected
+// to a cast group
+func (this *castchannel) MemberIds() []string {
+	this.Lock()
+	defer this.Unlock()
+	ids := make([]string, 0, len(this.members))
+	for id := range this.members {
+		ids = append(ids, id)
+	}
+	return ids
 }
 
 ////////////////////////////////////////////////////////////////////////////////
@@ -251,8 +322,8 @@ func (this *castchannel) GetMediaStatus() (int, error) {
 
 	// Get Media Status
 	payload := &PayloadHeader{Type: "GET_STATUS"}
-	if this.app == nil {
-		return 0, gopi.ErrOutOfOrder
+	if err := this.checkNamespace(CAST_NS_MEDIA); err != nil {
+		return 0, err
 	} else if err := this.send(CAST_DEFAULT_SENDER, this.app.TransportId, CAST_NS_MEDIA, payload.WithId(this.nextMessageId())); err != nil {
 		return 0, err
 	} else {
@@ -260,13 +331,25 @@ func (this *castchannel) GetMediaStatus() (int, error) {
 	}
 }
 
+func (this *castchannel) GetMultizoneStatus() (int, error) {
+	this.log.Debug2("<googlecast.Channel.GetMultizoneStatus>{ remote_addr=%v }", strconv.Quote(this.RemoteAddr()))
+
+	// Get group membership
+	payload := &PayloadHeader{Type: "GET_STATUS"}
+	if err := this.send(CAST_DEFAULT_SENDER, CAST_DEFAULT_RECEIVER, CAST_NS_MULTIZONE, payload.WithId(this.nextMessageId())); err != nil {
+		return 0, err
+	} else {
+		return payload.RequestId, nil
+	}
+}
+
 func (this *castchannel) ConnectMedia() (int, error) {
 	this.log.Debug2("<googlecast.Channel.ConnectMedia>{ remote_addr=%v }", strconv.Quote(this.RemoteAddr()))
 
 	// Connect to media to begin receiving events
 	payload := &PayloadHeader{Type: "CONNECT"}
-	if this.app == nil {
-		return 0, gopi.ErrOutOfOrder
+	if err := this.checkNamespace(CAST_NS_MEDIA); err != nil {
+		return 0, err
 	} else if err := this.send(CAST_DEFAULT_SENDER, this.app.TransportId, CAST_NS_CONN, payload.WithId(this.nextMessageId())); err != nil {
 		return 0, err
 	} else {
@@ -288,9 +371,343 @@ func (this *castchannel) DisconnectMedia() (int, error) {
 	}
 }
 
+////////////////////////////////////////////////////////////////////////////////
+// APPLICATION AND VOLUME
+
+// LaunchApp launches an application by identifier, replacing any
+// application which is running
+func (this *castchannel) LaunchApp(appId string) (int, error) {
+	this.log.Debug2("<googlecast.Channel.LaunchApp>{ remote_addr=%v app_id=%v }", strconv.Quote(this.RemoteAddr()), strconv.Quote(appId))
+
+	payload := &LaunchRequest{LaunchHeader, appId}
+	if appId == "" {
+		return 0, gopi.ErrBadParameter
+	}
+
+	// Record the request so that the session which replaces the
+	// current one is not reported as taken over
+	reqid := this.nextMessageId()
+	this.Lock()
+	this.launches[reqid] = castlaunch{appId, time.Now()}
+	this.Unlock()
+
+	if err := this.send(CAST_DEFAULT_SENDER, CAST_DEFAULT_RECEIVER, CAST_NS_RECV, payload.WithId(reqid)); err != nil {
+		this.launched(reqid, "")
+		return 0, err
+	} else {
+		return payload.RequestId, nil
+	}
+}
+
+// StopApp stops the current application session
+func (this *castchannel) StopApp() (int, error) {
+	this.log.Debug2("<googlecast.Channel.StopApp>{ remote_addr=%v }", strconv.Quote(this.RemoteAddr()))
+
+	payload := &StopRequest{PayloadHeader: PayloadHeader{Type: "STOP"}}
+	if this.app == nil {
+		return 0, gopi.ErrOutOfOrder
+	} else {
+		payload.SessionId = this.app.SessionId()
+	}
+	if err := this.send(CAST_DEFAULT_SENDER, CAST_DEFAULT_RECEIVER, CAST_NS_RECV, payload.WithId(this.nextMessageId())); err != nil {
+		return 0, err
+	} else {
+		return payload.RequestId, nil
+	}
+}
+
+// SetVolume sets the device volume level, between zero and one
+func (this *castchannel) SetVolume(value float32) (int, error) {
+	this.log.Debug2("<googlecast.Channel.SetVolume>{ remote_addr=%v value=%v }", strconv.Quote(this.RemoteAddr()), value)
+
+	payload := &ReceiverVolumeRequest{PayloadHeader{Type: "SET_VOLUME"}, VolumeRequest{Level: &value}}
+	if value < 0 || value > 1 {
+		return 0, gopi.ErrBadParameter
+	} else if err := this.send(CAST_DEFAULT_SENDER, CAST_DEFAULT_RECEIVER, CAST_NS_RECV, payload.WithId(this.nextMessageId())); err != nil {
+		return 0, err
+	} else {
+		return payload.RequestId, nil
+	}
+}
+
+// SetMuted mutes or unmutes the device
+func (this *castchannel) SetMuted(value bool) (int, error) {
+	this.log.Debug2("<googlecast.Channel.SetMuted>{ remote_addr=%v value=%v }", strconv.Quote(this.RemoteAddr()), value)
+
+	payload := &ReceiverVolumeRequest{PayloadHeader{Type: "SET_VOLUME"}, VolumeRequest{Muted: &value}}
+	if err := this.send(CAST_DEFAULT_SENDER, CAST_DEFAULT_RECEIVER, CAST_NS_RECV, payload.WithId(this.nextMessageId())); err != nil {
+		return 0, err
+	} else {
+		return payload.RequestId, nil
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// LOAD MEDIA
+
+// LoadMedia loads media into the current application, which needs to
+// support the media namespace
+func (this *castchannel) LoadMedia(request googlecast.MediaRequest) (int, error) {
+	this.log.Debug2("<googlecast.Channel.LoadMedia>{ remote_addr=%v content_id=%v }", strconv.Quote(this.RemoteAddr()), strconv.Quote(request.ContentId))
+
+	payload := &MediaLoadRequest{PayloadHeader{Type: "LOAD"}, newMediaItem(request), request.Autoplay, request.CurrentTime, request.ActiveTrackIds}
+	if request.ContentId == "" || request.ContentType == "" {
+		return 0, gopi.ErrBadParameter
+	} else if err := this.checkNamespace(CAST_NS_MEDIA); err != nil {
+		return 0, err
+	} else {
+		return this.sendMedia(payload)
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// MEDIA CONTROLS
+
+// SetPlay plays the current media when true, or stops the media
+// session when false
+func (this *castchannel) SetPlay(state bool) (int, error) {
+	this.log.Debug2("<googlecast.Channel.SetPlay>{ remote_addr=%v state=%v }", strconv.Quote(this.RemoteAddr()), state)
+
+	payload := &MediaHeader{PayloadHeader: PayloadHeader{Type: "PLAY"}}
+	if state == false {
+		payload.Type = "STOP"
+	}
+	if err := this.checkMediaCommand(googlecast.CAST_MEDIA_COMMAND_NONE); err != nil {
+		return 0, err
+	} else {
+		payload.MediaSessionId = this.media.MediaSessionId
+		return this.sendMedia(payload)
+	}
+}
+
+// SetPause pauses the current media when true, or resumes playing
+// when false
+func (this *castchannel) SetPause(state bool) (int, error) {
+	this.log.Debug2("<googlecast.Channel.SetPause>{ remote_addr=%v state=%v }", strconv.Quote(this.RemoteAddr()), state)
+
+	payload := &MediaHeader{PayloadHeader: PayloadHeader{Type: "PAUSE"}}
+	if state == false {
+		payload.Type = "PLAY"
+	}
+	if err := this.checkMediaCommand(googlecast.CAST_MEDIA_COMMAND_PAUSE); err != nil {
+		return 0, err
+	} else {
+		payload.MediaSessionId = this.media.MediaSessionId
+		return this.sendMedia(payload)
+	}
+}
+
+// SetSeek seeks to an absolute position in seconds
+func (this *castchannel) SetSeek(value float32) (int, error) {
+	this.log.Debug2("<googlecast.Channel.SetSeek>{ remote_addr=%v value=%v }", strconv.Quote(this.RemoteAddr()), value)
+
+	payload := &MediaSeekRequest{MediaHeader{PayloadHeader: PayloadHeader{Type: "SEEK"}}, value}
+	if value < 0 {
+		return 0, gopi.ErrBadParameter
+	} else if err := this.checkMediaCommand(googlecast.CAST_MEDIA_COMMAND_SEEK); err != nil {
+		return 0, err
+	} else {
+		payload.MediaSessionId = this.media.MediaSessionId
+		return this.sendMedia(payload)
+	}
+}
+
+// SetSkip seeks forward (when positive) or backward (when negative)
+// relative to the current position, in seconds
+func (this *castchannel) SetSkip(value float32) (int, error) {
+	this.log.Debug2("<googlecast.Channel.SetSkip>{ remote_addr=%v value=%v }", strconv.Quote(this.RemoteAddr()), value)
+
+	cmd := googlecast.CAST_MEDIA_COMMAND_SKIP_FORWARD
+	if value < 0 {
+		cmd = googlecast.CAST_MEDIA_COMMAND_SKIP_BACKWARD
+	}
+	payload := &MediaSkipRequest{MediaHeader{PayloadHeader: PayloadHeader{Type: "SEEK"}}, value}
+	if value == 0 {
+		return 0, gopi.ErrBadParameter
+	} else if err := this.checkMediaCommand(cmd); err != nil {
+		return 0, err
+	} else {
+		payload.MediaSessionId = this.media.MediaSessionId
+		return this.sendMedia(payload)
+	}
+}
+
+// SetStreamVolume sets the volume level of the media stream, between
+// zero and one
+func (this *castchannel) SetStreamVolume(value float32) (int, error) {
+	this.log.Debug2("<googlecast.Channel.SetStreamVolume>{ remote_addr=%v value=%v }", strconv.Quote(this.RemoteAddr()), value)
+
+	payload := &MediaVolumeRequest{MediaHeader{PayloadHeader: PayloadHeader{Type: "VOLUME"}}, VolumeRequest{Level: &value}}
+	if value < 0 || value > 1 {
+		return 0, gopi.ErrBadParameter
+	} else if err := this.checkMediaCommand(googlecast.CAST_MEDIA_COMMAND_STREAM_VOLUME); err != nil {
+		return 0, err
+	} else {
+		payload.MediaSessionId = this.media.MediaSessionId
+		return this.sendMedia(payload)
+	}
+}
+
+// SetStreamMuted mutes or unmutes the media stream
+func (this *castchannel) SetStreamMuted(value bool) (int, error) {
+	this.log.Debug2("<googlecast.Channel.SetStreamMuted>{ remote_addr=%v value=%v }", strconv.Quote(this.RemoteAddr()), value)
+
+	payload := &MediaVolumeRequest{MediaHeader{PayloadHeader: PayloadHeader{Type: "VOLUME"}}, VolumeRequest{Muted: &value}}
+	if err := this.checkMediaCommand(googlecast.CAST_MEDIA_COMMAND_STREAM_MUTE); err != nil {
+		return 0, err
+	} else {
+		payload.MediaSessionId = this.media.MediaSessionId
+		return this.sendMedia(payload)
+	}
+}
+
+// SetTrackNext jumps to the next item in the queue
+func (this *castchannel) SetTrackNext() (int, error) {
+	this.log.Debug2("<googlecast.Channel.SetTrackNext>{ remote_addr=%v }", strconv.Quote(this.RemoteAddr()))
+
+	payload := &MediaQueueRequest{MediaHeader{PayloadHeader: PayloadHeader{Type: "QUEUE_UPDATE"}}, 1}
+	if err := this.checkMediaCommand(googlecast.CAST_MEDIA_COMMAND_QUEUE_NEXT); err != nil {
+		return 0, err
+	} else {
+		payload.MediaSessionId = this.media.MediaSessionId
+		return this.sendMedia(payload)
+	}
+}
+
+// SetTrackPrev jumps to the previous item in the queue
+func (this *castchannel) SetTrackPrev() (int, error) {
+	this.log.Debug2("<googlecast.Channel.SetTrackPrev>{ remote_addr=%v }", strconv.Quote(this.RemoteAddr()))
+
+	payload := &MediaQueueRequest{MediaHeader{PayloadHeader: PayloadHeader{Type: "QUEUE_UPDATE"}}, -1}
+	if err := this.checkMediaCommand(googlecast.CAST_MEDIA_COMMAND_QUEUE_PREV); err != nil {
+		return 0, err
+	} else {
+		payload.MediaSessionId = this.media.MediaSessionId
+		return this.sendMedia(payload)
+	}
+}
+
+// SetSeekToLive seeks to the live edge of a live stream, which has
+// advanced since the media status was last received
+func (this *castchannel) SetSeekToLive() (int, error) {
+	this.log.Debug2("<googlecast.Channel.SetSeekToLive>{ remote_addr=%v }", strconv.Quote(this.RemoteAddr()))
+
+	payload := &MediaSeekRequest{MediaHeader{PayloadHeader: PayloadHeader{Type: "SEEK"}}, 0}
+	if err := this.checkMediaCommand(googlecast.CAST_MEDIA_COMMAND_SEEK); err != nil {
+		return 0, err
+	} else if end, ok := this.media.liveEdgeAt(time.Now()); ok == false {
+		return 0, gopi.ErrOutOfOrder
+	} else {
+		payload.MediaSessionId = this.media.MediaSessionId
+		payload.CurrentTime = end
+		return this.sendMedia(payload)
+	}
+}
+
+// SetPlaybackRate sets the playback rate, between half and
+// double normal speed
+func (this *castchannel) SetPlaybackRate(value float32) (int, error) {
+	this.log.Debug2("<googlecast.Channel.SetPlaybackRate>{ remote_addr=%v value=%v }", strconv.Quote(this.RemoteAddr()), value)
+
+	payload := &MediaPlaybackRateRequest{MediaHeader{PayloadHeader: PayloadHeader{Type: "SET_PLAYBACK_RATE"}}, value}
+	if value < 0.5 || value > 2 {
+		return 0, gopi.ErrBadParameter
+	} else if err := this.checkMediaCommand(googlecast.CAST_MEDIA_COMMAND_NONE); err != nil {
+		return 0, err
+	} else {
+		payload.MediaSessionId = this.media.MediaSessionId
+		return this.sendMedia(payload)
+	}
+}
+
+// SetActiveTracks sets the active tracks by identifier, or disables
+// all tracks when no identifiers are provided
+func (this *castchannel) SetActiveTracks(ids ...int) (int, error) {
+	this.log.Debug2("<googlecast.Channel.SetActiveTracks>{ remote_addr=%v ids=%v }", strconv.Quote(this.RemoteAddr()), ids)
+
+	// An empty array rather than nil disables all tracks
+	if ids == nil {
+		ids = []int{}
+	}
+	payload := &MediaTracksRequest{MediaHeader{PayloadHeader: PayloadHeader{Type: "EDIT_TRACKS_INFO"}}, &ids, nil}
+	if err := this.checkMediaCommand(googlecast.CAST_MEDIA_COMMAND_NONE); err != nil {
+		return 0, err
+	} else {
+		payload.MediaSessionId = this.media.MediaSessionId
+		return this.sendMedia(payload)
+	}
+}
+
+// SetTextTrackStyle sets the appearance of text tracks
+func (this *castchannel) SetTextTrackStyle(style googlecast.TextTrackStyle) (int, error) {
+	this.log.Debug2("<googlecast.Channel.SetTextTrackStyle>{ remote_addr=%v style=%+v }", strconv.Quote(this.RemoteAddr()), style)
+
+	payload := &MediaTracksRequest{MediaHeader{PayloadHeader: PayloadHeader{Type: "EDIT_TRACKS_INFO"}}, nil, newTextTrackStyle(&style)}
+	if style.FontScale < 0 {
+		return 0, gopi.ErrBadParameter
+	} else if err := this.checkMediaCommand(googlecast.CAST_MEDIA_COMMAND_NONE); err != nil {
+		return 0, err
+	} else {
+		payload.MediaSessionId = this.media.MediaSessionId
+		return this.sendMedia(payload)
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// APPLICATION AVAILABILITY
+
+// AppAvailability returns true for each application identifier
+// which can be launched on the device
+func (this *castchannel) AppAvailability(ctx context.Context, appIds ...string) (map[string]bool, error) {
+	this.log.Debug2("<googlecast.Channel.AppAvailability>{ remote_addr=%v app_ids=%v }", strconv.Quote(this.RemoteAddr()), appIds)
+
+	var response AppAvailabilityResponse
+	payload := &AppAvailabilityRequest{PayloadHeader{Type: "GET_APP_AVAILABILITY"}, appIds}
+	if len(appIds) == 0 {
+		return nil, gopi.ErrBadParameter
+	} else if data, err := this.request(ctx, CAST_DEFAULT_RECEIVER, CAST_NS_RECV, payload); err != nil {
+		return nil, err
+	} else if err := json.Unmarshal([]byte(data), &response); err != nil {
+		return nil, fmt.Errorf("GET_APP_AVAILABILITY: %w", err)
+	} else {
+		availability := make(map[string]bool, len(appIds))
+		for _, appId := range appIds {
+			availability[appId] = response.Availability[appId] == "APP_AVAILABLE"
+		}
+		return availability, nil
+	}
+}
+
 ////////////////////////////////////////////////////////////////////////////////
 // SEND MESSAGES
 
+// request sends a message and waits for the response with the same
+// request identifier, or until the context is cancelled
+func (this *castchannel) request(ctx context.Context, dest, ns string, payload Payload) (string, error) {
+	reqid := this.nextMessageId()
+	response := make(chan string, 1)
+
+	this.Lock()
+	this.pending[reqid] = response
+	this.Unlock()
+
+	defer func() {
+		this.Lock()
+		delete(this.pending, reqid)
+		this.Unlock()
+	}()
+
+	if err := this.send(CAST_DEFAULT_SENDER, dest, ns, payload.WithId(reqid)); err != nil {
+		return "", err
+	}
+	select {
+	case data := <-response:
+		return data, nil
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
 func (this *castchannel) send(source, dest, ns string, payload Payload) error {
 	this.log.Debug2("<googlecast.Channel.Send>{ source=%v dest=%v ns=%v payload=%v }", strconv.Quote(source), strconv.Quote(dest), strconv.Quote(ns), payload)
 
@@ -309,10 +726,14 @@ func (this *castchannel) send(source, dest, ns string, payload Payload) error {
 		proto.SetDefaults(message)
 		if data, err := proto.Marshal(message); err != nil {
 			return err
-		} else if err := binary.Write(this.conn, binary.BigEndian, uint32(len(data))); err != nil {
-			return err
-		} else if _, err := this.conn.Write(data); err != nil {
-			return err
+		} else {
+			// Write length and message together, so that messages sent
+			// from different goroutines are not interleaved
+			buf := make([]byte, 4, 4+len(data))
+			binary.BigEndian.PutUint32(buf, uint32(len(data)))
+			if _, err := this.conn.Write(append(buf, data...)); err != nil {
+				return err
+			}
 		}
 	}
 
@@ -336,7 +757,7 @@ FOR_LOOP:
 				if _, err := this.GetStatus(); err != nil {
 					this.log.Warn("GetStatus: %v", err)
 				}
-			} else if this.app != nil && this.media == nil {
+			} else if this.app != nil && this.media == nil && this.app.SupportsNamespace(CAST_NS_MEDIA) {
 				if _, err := this.ConnectMedia(); err != nil {
 					this.log.Warn("ConnectMedia: %v", err)
 				} else if _, err := this.GetMediaStatus(); err != nil {
@@ -395,6 +816,22 @@ func (this *castchannel) receive_message(data []byte) error {
 	if err := proto.Unmarshal(data, message); err != nil {
 		return err
 	}
+
+	// Record time message was received
+	this.Lock()
+	this.received = time.Now()
+	this.Unlock()
+
+	// Deliver responses to pending requests, which have a response
+	// type rather than a type
+	var header PayloadHeader
+	if err := json.Unmarshal([]byte(message.GetPayloadUtf8()), &header); err == nil {
+		this.reply(header.RequestId, message.GetPayloadUtf8())
+		if header.Type == "" && header.ResponseType != "" {
+			return nil
+		}
+	}
+
 	ns := message.GetNamespace()
 	switch ns {
 	case CAST_NS_RECV:
@@ -405,11 +842,61 @@ func (this *castchannel) receive_message(data []byte) error {
 		return this.receive_message_connection(message)
 	case CAST_NS_MEDIA:
 		return this.receive_message_media(message)
+	case CAST_NS_MULTIZONE:
+		return this.receive_message_multizone(message)
 	default:
 		return fmt.Errorf("Ignoring message with namespace %v", strconv.Quote(ns))
 	}
 }
 
+// reply delivers a response to a pending request
+func (this *castchannel) reply(reqid int, data string) {
+	this.Lock()
+	defer this.Unlock()
+	if response, exists := this.pending[reqid]; exists && reqid != 0 {
+		select {
+		case response <- data:
+		default:
+		}
+	}
+}
+
+// checkNamespace returns an error if there is no application, or the
+// application does not support a namespace
+func (this *castchannel) checkNamespace(ns string) error {
+	if this.app == nil {
+		return gopi.ErrOutOfOrder
+	} else if this.app.SupportsNamespace(ns) == false {
+		return fmt.Errorf("%w: %v", googlecast.ErrUnsupportedNamespace, strconv.Quote(ns))
+	} else {
+		return nil
+	}
+}
+
+// checkMediaCommand returns an error if there is no media session, or
+// the receiver does not support a media command
+func (this *castchannel) checkMediaCommand(cmd googlecast.MediaCommand) error {
+	if err := this.checkNamespace(CAST_NS_MEDIA); err != nil {
+		return err
+	} else if this.media == nil {
+		return gopi.ErrOutOfOrder
+	} else if this.media.Supports(cmd) == false {
+		return &googlecast.UnsupportedCommandError{Command: cmd}
+	} else {
+		return nil
+	}
+}
+
+// sendMedia sends a media command to the application
+func (this *castchannel) sendMedia(payload Payload) (int, error) {
+	reqid := this.nextMessageId()
+	if err := this.send(CAST_DEFAULT_SENDER, this.app.TransportId, CAST_NS_MEDIA, payload.WithId(reqid)); err != nil {
+		return 0, err
+	} else {
+		return reqid, nil
+	}
+}
+
 func (this *castchannel) receive_message_receiver(message *pb.CastMessage) error {
 	var header PayloadHeader
 	var receiver_status ReceiverStatusResponse
@@ -422,11 +909,21 @@ func (this *castchannel) receive_message_receiver(message *pb.CastMessage) error
 		if err := json.Unmarshal([]byte(message.GetPayloadUtf8()), &receiver_status); err != nil {
 			return fmt.Errorf("RECEIVER_STATUS: %w", err)
 		}
-		// Set application and volume
+		// Set application, volume, input and standby
 		this.set_application(header.RequestId, receiver_status.Status.Applications)
 		this.set_volume(header.RequestId, receiver_status.Status.Volume)
+		this.set_input(header.RequestId, receiver_status.Status.IsActiveInput)
+		this.set_standby(header.RequestId, receiver_status.Status.IsStandBy)
 		// Return success
 		return nil
+	case "LAUNCH_ERROR":
+		var launch_error LaunchErrorResponse
+		if err := json.Unmarshal([]byte(message.GetPayloadUtf8()), &launch_error); err != nil {
+			return fmt.Errorf("LAUNCH_ERROR: %w", err)
+		}
+		// Forget the launch which failed
+		this.launched(header.RequestId, "")
+		return fmt.Errorf("LAUNCH_ERROR: %v", launch_error.Reason)
 	default:
 		return fmt.Errorf("Ignoring message %v in namespace %v", strconv.Quote(header.Type), strconv.Quote(message.GetNamespace()))
 	}
@@ -478,8 +975,13 @@ func (this *castchannel) receive_message_media(message *pb.CastMessage) error {
 			return err
 		}
 		// Update media
-		for _, media := range media_status.Status {
-			this.set_media(header.RequestId, &media)
+		if len(media_status.Status) == 0 {
+			this.set_media(header.RequestId, nil)
+		}
+		for i := range media_status.Status {
+			media := &media_status.Status[i]
+			media.received = this.LastReceived()
+			this.set_media(header.RequestId, media)
 		}
 	default:
 		fmt.Println(message)
@@ -489,8 +991,59 @@ func (this *castchannel) receive_message_media(message *pb.CastMessage) error {
 	return nil
 }
 
+func (this *castchannel) receive_message_multizone(message *pb.CastMessage) error {
+	var header PayloadHeader
+
+	if err := json.Unmarshal([]byte(*message.PayloadUtf8), &header); err != nil {
+		return err
+	}
+	switch header.Type {
+	case "MULTIZONE_STATUS":
+		var multizone_status MultizoneStatusResponse
+		if err := json.Unmarshal([]byte(message.GetPayloadUtf8()), &multizone_status); err != nil {
+			return fmt.Errorf("MULTIZONE_STATUS: %w", err)
+		}
+		this.set_members(header.RequestId, multizone_status.Status.Devices)
+	case "DEVICE_ADDED", "DEVICE_UPDATED":
+		var device MultizoneDeviceResponse
+		if err := json.Unmarshal([]byte(message.GetPayloadUtf8()), &device); err != nil {
+			return fmt.Errorf("%v: %w", header.Type, err)
+		}
+		this.set_member(header.RequestId, device.Device)
+	case "DEVICE_REMOVED":
+		var device MultizoneDeviceResponse
+		if err := json.Unmarshal([]byte(message.GetPayloadUtf8()), &device); err != nil {
+			return fmt.Errorf("DEVICE_REMOVED: %w", err)
+		}
+		this.remove_member(header.RequestId, device.DeviceId)
+	default:
+		return fmt.Errorf("Ignoring message %v in namespace %v", strconv.Quote(header.Type), strconv.Quote(message.GetNamespace()))
+	}
+	// Return success
+	return nil
+}
+
 func (this *castchannel) set_application(reqid int, values []application) {
 	var set bool
+	prev := this.state()
+
+	// A session started by a launch is often broadcast with a zero
+	// request identifier, so match launches by application too
+	appId := ""
+	if len(values) > 0 && values[0].session() != this.app.session() {
+		appId = values[0].AppId
+	}
+	launched := this.launched(reqid, appId)
+
+	// Retain the time the session started
+	if len(values) > 0 {
+		if this.app != nil && this.app.SessionId_ == values[0].SessionId_ {
+			values[0].started = this.app.started
+		} else {
+			values[0].started = time.Now()
+		}
+	}
+
 	if len(values) == 0 && this.app == nil {
 		// Do nothing
 	} else if len(values) == 0 && this.app != nil {
@@ -507,14 +1060,58 @@ func (this *castchannel) set_application(reqid int, values []application) {
 	}
 	if set {
 		this.set_media(reqid, nil)
-		this.Emit(&castevent{
-			googlecast.CAST_EVENT_APPLICATION_UPDATED, this, nil, this, reqid,
-		})
+		this.emit(googlecast.CAST_EVENT_APPLICATION_UPDATED, reqid, prev)
+		this.set_session(reqid, prev, launched)
 	}
 }
 
+// set_session emits session events when the session of an application
+// other than the idle screen starts, ends or is replaced by a session
+// started by another sender. When the session is replaced in response
+// to a launch from this channel, the session ends and another starts
+func (this *castchannel) set_session(reqid int, prev *caststate, launched bool) {
+	if from, to := prev.app.session(), this.app.session(); from == to {
+		return
+	} else if from == "" {
+		this.emit(googlecast.CAST_EVENT_SESSION_STARTED, reqid, prev)
+	} else if to == "" {
+		this.emit(googlecast.CAST_EVENT_SESSION_ENDED, reqid, prev)
+	} else if launched {
+		this.emit(googlecast.CAST_EVENT_SESSION_ENDED, reqid, prev)
+		this.emit(googlecast.CAST_EVENT_SESSION_STARTED, reqid, prev)
+	} else {
+		this.emit(googlecast.CAST_EVENT_SESSION_TAKEN_OVER, reqid, prev)
+	}
+}
+
+// launched returns true and forgets the launch if a request
+// identifier is that of a launch from this channel, or otherwise
+// if the application is being launched from this channel. Launches
+// which have not been answered within the timeout are forgotten
+func (this *castchannel) launched(reqid int, appId string) bool {
+	this.Lock()
+	defer this.Unlock()
+	for id, launch := range this.launches {
+		if time.Since(launch.ts) > LAUNCH_TIMEOUT {
+			delete(this.launches, id)
+		}
+	}
+	if _, exists := this.launches[reqid]; exists && reqid != 0 {
+		delete(this.launches, reqid)
+		return true
+	}
+	for id, launch := range this.launches {
+		if appId != "" && launch.appId == appId {
+			delete(this.launches, id)
+			return true
+		}
+	}
+	return false
+}
+
 func (this *castchannel) set_volume(reqid int, value volume) {
 	var set bool
+	prev := this.state()
 	if this.volume == nil {
 		this.volume = &value
 		set = true
@@ -523,21 +1120,108 @@ func (this *castchannel) set_volume(reqid int, value volume) {
 		set = true
 	}
 	if set {
-		this.Emit(&castevent{
-			googlecast.CAST_EVENT_VOLUME_UPDATED, this, nil, this, reqid,
-		})
+		this.emit(googlecast.CAST_EVENT_VOLUME_UPDATED, reqid, prev)
+	}
+}
+
+func (this *castchannel) set_input(reqid int, value *bool) {
+	if value == nil {
+		// Ignore when omitted from the status
+		return
+	}
+	prev := this.state()
+	if this.input == nil || *this.input != *value {
+		this.input = value
+		this.emit(googlecast.CAST_EVENT_INPUT_UPDATED, reqid, prev)
+	}
+}
+
+func (this *castchannel) set_standby(reqid int, value *bool) {
+	if value == nil {
+		// Ignore when omitted from the status
+		return
+	}
+	prev := this.state()
+	if this.standby == nil || *this.standby != *value {
+		this.standby = value
+		this.emit(googlecast.CAST_EVENT_STANDBY_UPDATED, reqid, prev)
 	}
 }
 
 func (this *castchannel) set_media(reqid int, value *media) {
-	var set bool
-	if this.media != value || value.Equals(this.media) == false {
-		this.media = value
-		set = true
+	prev := this.state()
+
+	// Status updates omit media information when it has not changed
+	if value != nil && this.media != nil && value.MediaSessionId == this.media.MediaSessionId && value.Media.ContentId == "" {
+		value.Media = this.media.Media
 	}
-	if set {
-		this.Emit(&castevent{
-			googlecast.CAST_EVENT_MEDIA_UPDATED, this, nil, this, reqid,
+	changes := value.Changes(this.media)
+
+	// Media is always replaced, so that the current time is updated
+	// even when there are no changes to report
+	this.media = value
+	for _, type_ := range changes {
+		this.emit(type_, reqid, prev)
+	}
+}
+
+// state returns a snapshot of the current channel state
+func (this *castchannel) state() *caststate {
+	return &caststate{
+		app:     this.app,
+		volume:  this.volume,
+		media:   this.media,
+		input:   this.ActiveInput(),
+		standby: this.StandBy(),
+	}
+}
+
+// emit an event with the current state and the state before the change
+func (this *castchannel) emit(type_ googlecast.EventType, reqid int, prev *caststate) {
+	this.Emit(&castevent{type_: type_, source_: this, channel_: this, reqid_: reqid, state_: this.state(), prev_: prev})
+}
+
+func (this *castchannel) set_members(reqid int, values []multizonedevice) {
+	this.Lock()
+	this.members = make(map[string]multizonedevice, len(values))
+	ids := make([]string, 0, len(values))
+	for _, value := range values {
+		id := multizoneId(value.DeviceId)
+		this.members[id] = value
+		ids = append(ids, id)
+	}
+	this.Unlock()
+	this.Emit(&castgroupevent{
+		castevent{type_: googlecast.CAST_EVENT_GROUP_UPDATED, source_: this, channel_: this, reqid_: reqid, state_: this.state()}, ids, nil,
+	})
+}
+
+func (this *castchannel) set_member(reqid int, value multizonedevice) {
+	id := multizoneId(value.DeviceId)
+	type_ := googlecast.CAST_EVENT_GROUP_MEMBER_UPDATED
+	this.Lock()
+	if this.members == nil {
+		this.members = make(map[string]multizonedevice)
+	}
+	if _, exists := this.members[id]; exists == false {
+		type_ = googlecast.CAST_EVENT_GROUP_MEMBER_ADDED
+	}
+	this.members[id] = value
+	this.Unlock()
+	this.Emit(&castgroupevent{
+		castevent{type_: type_, source_: this, channel_: this, reqid_: reqid, state_: this.state()}, []string{id}, nil,
+	})
+}
+
+func (this *castchannel) remove_member(reqid int, value string) {
+	id := multizoneId(value)
+	this.Lock()
+	_, exists := this.members[id]
+	delete(this.members, id)
+	this.Unlock()
+	if exists {
+		this.Emit(&castgroupevent{
+			castevent{type_: googlecast.CAST_EVENT_GROUP_MEMBER_REMOVED, source_: this, channel_: this, reqid_: reqid, state_: this.state()}, []string{id}, nil,
 		})
 	}
 }
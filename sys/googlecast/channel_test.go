@@ -0,0 +1,274 @@
+package googlecast
+
+import (
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	// Frameworks
+	googlecast "github.com/djthorpe/googlecast"
+	gopi "github.com/djthorpe/gopi"
+	logger "github.com/djthorpe/gopi/sys/logger"
+	proto "github.com/golang/protobuf/proto"
+
+	// Protocol buffers
+	pb "github.com/djthorpe/googlecast/rpc/protobuf/googlecast"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// castserver accepts channel connections on the loopback interface,
+// records messages received and sends messages to connected channels
+type castserver struct {
+	sync.Mutex
+	net.Listener
+	conns    []net.Conn
+	messages chan *pb.CastMessage
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// SESSIONS
+
+func TestChannelSession_000(t *testing.T) {
+	tests := []struct {
+		name     string
+		prev     []application
+		value    []application
+		launch   bool
+		reqid    int
+		expected []googlecast.EventType
+	}{
+		{"started", nil, []application{{AppId: "B", SessionId_: "2"}}, false, 1, []googlecast.EventType{
+			googlecast.CAST_EVENT_APPLICATION_UPDATED,
+			googlecast.CAST_EVENT_SESSION_STARTED,
+		}},
+		{"ended", []application{{AppId: "A", SessionId_: "1"}}, []application{{AppId: "I", SessionId_: "3", IsIdleScreen: true}}, false, 1, []googlecast.EventType{
+			googlecast.CAST_EVENT_APPLICATION_UPDATED,
+			googlecast.CAST_EVENT_SESSION_ENDED,
+		}},
+		{"taken_over", []application{{AppId: "A", SessionId_: "1"}}, []application{{AppId: "B", SessionId_: "2"}}, false, 1, []googlecast.EventType{
+			googlecast.CAST_EVENT_APPLICATION_UPDATED,
+			googlecast.CAST_EVENT_SESSION_TAKEN_OVER,
+		}},
+		{"launched", []application{{AppId: "A", SessionId_: "1"}}, []application{{AppId: "B", SessionId_: "2"}}, true, 1, []googlecast.EventType{
+			googlecast.CAST_EVENT_APPLICATION_UPDATED,
+			googlecast.CAST_EVENT_SESSION_ENDED,
+			googlecast.CAST_EVENT_SESSION_STARTED,
+		}},
+		{"launched_broadcast", []application{{AppId: "A", SessionId_: "1"}}, []application{{AppId: "B", SessionId_: "2"}}, true, 0, []googlecast.EventType{
+			googlecast.CAST_EVENT_APPLICATION_UPDATED,
+			googlecast.CAST_EVENT_SESSION_ENDED,
+			googlecast.CAST_EVENT_SESSION_STARTED,
+		}},
+	}
+	for _, test := range tests {
+		this := &castchannel{launches: make(map[int]castlaunch)}
+		this.set_application(0, test.prev)
+		if test.launch {
+			this.launches[1] = castlaunch{"B", time.Now()}
+		}
+
+		// Collect events while the status is set
+		evts := this.Subscribe()
+		types := make(chan []googlecast.EventType)
+		go func() {
+			values := make([]googlecast.EventType, 0)
+			for evt := range evts {
+				values = append(values, evt.(googlecast.Event).Type())
+			}
+			types <- values
+		}()
+		this.set_application(test.reqid, test.value)
+		this.Unsubscribe(evts)
+
+		if values := <-types; reflect.DeepEqual(values, test.expected) == false {
+			t.Errorf("%v: Unexpected events %v, expected %v", test.name, values, test.expected)
+		} else if len(this.launches) != 0 {
+			t.Errorf("%v: Expected launch to be forgotten", test.name)
+		}
+	}
+}
+
+func TestChannelLaunch_000(t *testing.T) {
+	this := &castchannel{launches: make(map[int]castlaunch)}
+	this.launches[1] = castlaunch{"A", time.Now()}
+	this.launches[2] = castlaunch{"B", time.Now().Add(-LAUNCH_TIMEOUT - time.Second)}
+
+	// Launch which has timed out is forgotten
+	if this.launched(0, "B") {
+		t.Error("Expected timed out launch not to match")
+	} else if _, exists := this.launches[2]; exists {
+		t.Error("Expected timed out launch to be forgotten")
+	}
+
+	// Launch error forgets the launch
+	message := &pb.CastMessage{
+		Namespace:   proto.String(CAST_NS_RECV),
+		PayloadUtf8: proto.String(`{"type":"LAUNCH_ERROR","requestId":1,"reason":"NOT_FOUND"}`),
+	}
+	if err := this.receive_message_receiver(message); err == nil {
+		t.Error("Expected launch error to be returned")
+	} else if len(this.launches) != 0 {
+		t.Error("Expected failed launch to be forgotten")
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// MEDIA CONTROLS
+
+func TestChannelMediaCommand_000(t *testing.T) {
+	// Unsupported media commands return an error which can be unwrapped
+	this := &castchannel{
+		log:   testLogger(t),
+		app:   &application{AppId: "A", SessionId_: "1"},
+		media: &media{MediaSessionId: 1, SupportedMediaCommands: googlecast.CAST_MEDIA_COMMAND_PAUSE},
+	}
+	tests := []struct {
+		name     string
+		call     func() (int, error)
+		expected googlecast.MediaCommand
+	}{
+		{"seek", func() (int, error) { return this.SetSeek(10) }, googlecast.CAST_MEDIA_COMMAND_SEEK},
+		{"volume", func() (int, error) { return this.SetStreamVolume(0.5) }, googlecast.CAST_MEDIA_COMMAND_STREAM_VOLUME},
+		{"mute", func() (int, error) { return this.SetStreamMuted(true) }, googlecast.CAST_MEDIA_COMMAND_STREAM_MUTE},
+		{"skip", func() (int, error) { return this.SetSkip(-10) }, googlecast.CAST_MEDIA_COMMAND_SKIP_BACKWARD},
+		{"next", func() (int, error) { return this.SetTrackNext() }, googlecast.CAST_MEDIA_COMMAND_QUEUE_NEXT},
+	}
+	for _, test := range tests {
+		var unsupported *googlecast.UnsupportedCommandError
+		if _, err := test.call(); err == nil {
+			t.Errorf("%v: Expected error", test.name)
+		} else if errors.As(fmt.Errorf("%v: %w", test.name, err), &unsupported) == false {
+			t.Errorf("%v: Unexpected error %v", test.name, err)
+		} else if unsupported.Command != test.expected {
+			t.Errorf("%v: Unexpected command %v", test.name, unsupported.Command)
+		}
+	}
+
+	// Commands without a media session are out of order
+	this.media = nil
+	if _, err := this.SetPause(true); errors.Is(err, gopi.ErrOutOfOrder) == false {
+		t.Error("Unexpected error", err)
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// UTILS
+
+// testLogger returns a logger which discards output
+func testLogger(t *testing.T) gopi.Logger {
+	t.Helper()
+	if log, err := gopi.Open(logger.Config{Level: logger.LOG_NONE}, nil); err != nil {
+		t.Fatal(err)
+		return nil
+	} else {
+		return log.(gopi.Logger)
+	}
+}
+
+// newCastServer returns a server which channels can connect to
+func newCastServer(t *testing.T) *castserver {
+	t.Helper()
+
+	// Borrow the test certificate from a TLS server
+	https := httptest.NewUnstartedServer(nil)
+	https.StartTLS()
+	certificate := https.TLS.Certificates[0]
+	https.Close()
+
+	this := &castserver{messages: make(chan *pb.CastMessage, 100)}
+	if listener, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{Certificates: []tls.Certificate{certificate}}); err != nil {
+		t.Fatal(err)
+	} else {
+		this.Listener = listener
+	}
+	go func() {
+		for {
+			if conn, err := this.Accept(); err != nil {
+				return
+			} else {
+				this.Lock()
+				this.conns = append(this.conns, conn)
+				this.Unlock()
+				go this.receive(conn)
+			}
+		}
+	}()
+	return this
+}
+
+// Port returns the port which the server is listening on
+func (this *castserver) Port() uint {
+	return uint(this.Addr().(*net.TCPAddr).Port)
+}
+
+// Record returns a service record for a device which has an id,
+// name and model and which is served by this server
+func (this *castserver) Record(id, name, model string) *servicerecord {
+	return &servicerecord{
+		Name_:    name,
+		Service_: SERVICE_TYPE_GOOGLECAST,
+		Port_:    this.Port(),
+		Text_:    []string{"id=" + id, "fn=" + name, "md=" + model},
+		IP4_:     []net.IP{net.IPv4(127, 0, 0, 1)},
+	}
+}
+
+// Send a message from the receiver to all connected channels
+func (this *castserver) Send(ns, payload string) {
+	message := &pb.CastMessage{
+		ProtocolVersion: pb.CastMessage_CASTV2_1_0.Enum(),
+		SourceId:        proto.String(CAST_DEFAULT_RECEIVER),
+		DestinationId:   proto.String("*"),
+		Namespace:       proto.String(ns),
+		PayloadType:     pb.CastMessage_STRING.Enum(),
+		PayloadUtf8:     proto.String(payload),
+	}
+	data, _ := proto.Marshal(message)
+	buf := make([]byte, 4, 4+len(data))
+	binary.BigEndian.PutUint32(buf, uint32(len(data)))
+	this.Lock()
+	defer this.Unlock()
+	for _, conn := range this.conns {
+		conn.Write(append(buf, data...))
+	}
+}
+
+// Close the server and any connections
+func (this *castserver) Close() error {
+	this.Lock()
+	defer this.Unlock()
+	for _, conn := range this.conns {
+		conn.Close()
+	}
+	return this.Listener.Close()
+}
+
+func (this *castserver) receive(conn net.Conn) {
+	for {
+		var length uint32
+		if err := binary.Read(conn, binary.BigEndian, &length); err != nil {
+			return
+		}
+		data := make([]byte, length)
+		if _, err := io.ReadFull(conn, data); err != nil {
+			return
+		}
+		message := &pb.CastMessage{}
+		if err := proto.Unmarshal(data, message); err == nil {
+			select {
+			case this.messages <- message:
+			default:
+			}
+		}
+	}
+}
@@ -12,11 +12,14 @@ import (
 	"fmt"
 	"math/rand"
 	"net"
+	"path"
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	// Frameworks
+	googlecast "github.com/djthorpe/googlecast"
 	gopi "github.com/djthorpe/gopi"
 )
 
@@ -26,14 +29,30 @@ import (
 type castdevice struct {
 	gopi.RPCServiceRecord
 	sync.Mutex
-	txt_ map[string]string
+	txt_      map[string]string
+	firstSeen time.Time
+	lastSeen  time.Time
+	loaded    time.Time
+	channel   *castchannel
+
+	// Port for the setup API, which is SETUP_PORT when zero
+	setupPort uint
 }
 
+////////////////////////////////////////////////////////////////////////////////
+// CONSTANTS
+
+const (
+	// A device with a connected channel is reachable when a message
+	// has been received within this period
+	DELTA_REACHABLE_TIME = 3 * STATUS_INTERVAL
+)
+
 ////////////////////////////////////////////////////////////////////////////////
 // STRINGIFY
 
 func (this *castdevice) String() string {
-	return fmt.Sprintf("<googlecast.Device>{ id=%v name=%v model=%v service=%v state=%v }", this.Id(), strconv.Quote(this.Name()), strconv.Quote(this.Model()), strconv.Quote(this.Service()), this.State())
+	return fmt.Sprintf("<googlecast.Device>{ id=%v name=%v model=%v service=%v state=%v capabilities=%v last_seen=%v reachable=%v verified=%v }", this.Id(), strconv.Quote(this.Name()), strconv.Quote(this.Model()), strconv.Quote(this.Service()), this.State(), this.Capabilities(), this.LastSeen().Format(time.RFC3339), this.Reachable(), this.Verified())
 }
 
 ////////////////////////////////////////////////////////////////////////////////
@@ -65,6 +84,75 @@ func (this *castdevice) State() uint {
 	}
 }
 
+func (this *castdevice) Capabilities() googlecast.DeviceCapability {
+	if value := this.txt("ca"); value == "" {
+		return googlecast.CAST_CAPABILITY_NONE
+	} else if value_, err := strconv.ParseUint(value, 10, 32); err != nil {
+		return googlecast.CAST_CAPABILITY_NONE
+	} else {
+		return googlecast.DeviceCapability(value_)
+	}
+}
+
+func (this *castdevice) FirstSeen() time.Time {
+	this.Mutex.Lock()
+	defer this.Mutex.Unlock()
+	return this.firstSeen
+}
+
+func (this *castdevice) LastSeen() time.Time {
+	this.Mutex.Lock()
+	defer this.Mutex.Unlock()
+	if this.channel != nil {
+		if received := this.channel.LastReceived(); received.After(this.lastSeen) {
+			return received
+		}
+	}
+	return this.lastSeen
+}
+
+// Reachable returns true if a channel is connected to the device and
+// a message has recently been received on it
+func (this *castdevice) Reachable() bool {
+	this.Mutex.Lock()
+	defer this.Mutex.Unlock()
+	if this.channel == nil {
+		return false
+	} else if received := this.channel.LastReceived(); received.IsZero() {
+		return false
+	} else {
+		return time.Since(received) < DELTA_REACHABLE_TIME
+	}
+}
+
+// Verified returns false if the device was loaded from the
+// cache and has not yet been seen on the network
+func (this *castdevice) Verified() bool {
+	this.Mutex.Lock()
+	defer this.Mutex.Unlock()
+	return this.loaded.IsZero()
+}
+
+// Matches returns true if the device matches all the non-empty
+// fields of the query
+func (this *castdevice) Matches(query googlecast.DeviceQuery) bool {
+	if query.Id != "" && query.Id != this.Id() {
+		return false
+	}
+	if query.Name != "" && query.Name != this.Name() {
+		if matched, err := path.Match(query.Name, this.Name()); err != nil || matched == false {
+			return false
+		}
+	}
+	if query.Model != "" && query.Model != this.Model() {
+		return false
+	}
+	if this.Capabilities()&query.Capabilities != query.Capabilities {
+		return false
+	}
+	return true
+}
+
 func (this *castdevice) Equals(other *castdevice) bool {
 	if this.Id() != other.Id() {
 		return false
@@ -81,12 +169,65 @@ func (this *castdevice) Equals(other *castdevice) bool {
 	if this.State() != other.State() {
 		return false
 	}
+	if this.Capabilities() != other.Capabilities() {
+		return false
+	}
 	return true
 }
 
 ////////////////////////////////////////////////////////////////////////////////
 // PRIVATE METHODS
 
+// seen updates the last seen time, and sets the first seen
+// time if not yet set
+func (this *castdevice) seen(ts time.Time) {
+	this.Mutex.Lock()
+	defer this.Mutex.Unlock()
+	if this.firstSeen.IsZero() {
+		this.firstSeen = ts
+	}
+	if ts.After(this.lastSeen) {
+		this.lastSeen = ts
+	}
+}
+
+// setRecord replaces the service record for the device, and marks
+// the device as verified
+func (this *castdevice) setRecord(record gopi.RPCServiceRecord) {
+	this.Mutex.Lock()
+	defer this.Mutex.Unlock()
+	this.RPCServiceRecord = record
+	this.txt_ = nil
+	this.loaded = time.Time{}
+}
+
+// record returns the service record in a form which can be cached
+func (this *castdevice) record() servicerecord {
+	this.Mutex.Lock()
+	defer this.Mutex.Unlock()
+	return NewServiceRecord(this.RPCServiceRecord)
+}
+
+// setChannel sets the channel connected to the device, or nil
+func (this *castdevice) setChannel(channel *castchannel) {
+	this.Mutex.Lock()
+	defer this.Mutex.Unlock()
+	this.channel = channel
+}
+
+// expired returns true if the device has not been seen
+// since the time provided and is not reachable. Devices loaded
+// from the cache are given the same period to be verified
+func (this *castdevice) expired(since time.Time) bool {
+	this.Mutex.Lock()
+	loaded := this.loaded
+	this.Mutex.Unlock()
+	if loaded.After(since) {
+		return false
+	}
+	return this.LastSeen().Before(since) && this.Reachable() == false
+}
+
 func (this *castdevice) txt(key string) string {
 	this.Mutex.Lock()
 	defer this.Mutex.Unlock()
@@ -105,27 +246,43 @@ func (this *castdevice) txt(key string) string {
 	}
 }
 
+// ips returns the IPv4 or IPv6 addresses for the device
+func (this *castdevice) ips(flag gopi.RPCFlag) []net.IP {
+	this.Mutex.Lock()
+	defer this.Mutex.Unlock()
+	switch flag {
+	case gopi.RPC_FLAG_INET_V4:
+		return this.RPCServiceRecord.IP4()
+	case gopi.RPC_FLAG_INET_V6:
+		return this.RPCServiceRecord.IP6()
+	default:
+		return nil
+	}
+}
+
+// addr returns an address for the device, reading the addresses
+// with the lock held
 func (this *castdevice) addr(flag gopi.RPCFlag) (net.IP, error) {
 	switch flag & (gopi.RPC_FLAG_INET_V4 | gopi.RPC_FLAG_INET_V6) {
 	case gopi.RPC_FLAG_INET_V4:
-		ip4 := this.RPCServiceRecord.IP4()
+		ip4 := this.ips(gopi.RPC_FLAG_INET_V4)
 		if len(ip4) == 0 {
 			return nil, gopi.ErrNotFound
 		} else if flag&gopi.RPC_FLAG_SERVICE_ANY != 0 {
 			// Return any
-			index := rand.Intn(len(ip4) - 1)
+			index := rand.Intn(len(ip4))
 			return ip4[index], nil
 		} else {
 			// Return first
 			return ip4[0], nil
 		}
 	case gopi.RPC_FLAG_INET_V6:
-		ip6 := this.RPCServiceRecord.IP6()
+		ip6 := this.ips(gopi.RPC_FLAG_INET_V6)
 		if len(ip6) == 0 {
 			return nil, gopi.ErrNotFound
 		} else if flag&gopi.RPC_FLAG_SERVICE_ANY != 0 {
 			// Return any
-			index := rand.Intn(len(ip6) - 1)
+			index := rand.Intn(len(ip6))
 			return ip6[index], nil
 		} else {
 			// Return first
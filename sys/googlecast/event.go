@@ -10,6 +10,7 @@ package googlecast
 
 import (
 	"fmt"
+	"time"
 
 	// Frameworks
 	googlecast "github.com/djthorpe/googlecast"
@@ -25,6 +26,24 @@ type castevent struct {
 	device_  googlecast.Device
 	channel_ googlecast.Channel
 	reqid_   int
+	state_   *caststate
+	prev_    *caststate
+	seq_     uint64
+	ts_      time.Time
+}
+
+// castgroupevent is emitted when group membership changes, and
+// identifies the affected members
+type castgroupevent struct {
+	castevent
+	ids_     []string
+	members_ []googlecast.Device
+}
+
+// casterrorevent ends a subscription with an error
+type casterrorevent struct {
+	castevent
+	err error
 }
 
 ////////////////////////////////////////////////////////////////////////////////
@@ -50,15 +69,75 @@ func (this *castevent) Channel() googlecast.Channel {
 	return this.channel_
 }
 
+func (this *castevent) Sequence() uint64 {
+	return this.seq_
+}
+
+func (this *castevent) Timestamp() time.Time {
+	return this.ts_
+}
+
+// stamp sets the sequence number and timestamp when the event is emitted
+func (this *castevent) stamp(seq uint64, ts time.Time) {
+	this.seq_ = seq
+	this.ts_ = ts
+}
+
+func (this *castevent) State() googlecast.State {
+	if this.state_ == nil {
+		return nil
+	} else {
+		return this.state_
+	}
+}
+
+func (this *castevent) Previous() googlecast.State {
+	if this.prev_ == nil {
+		return nil
+	} else {
+		return this.prev_
+	}
+}
+
 ////////////////////////////////////////////////////////////////////////////////
 // STRINGIFY
 
 func (this *castevent) String() string {
 	if this.channel_ != nil {
-		return fmt.Sprintf("<%s>{ %v channel=%v device=%v reqid=%v }", this.Name(), this.type_, this.channel_, this.device_, this.reqid_)
+		return fmt.Sprintf("<%s>{ %v seq=%v channel=%v device=%v reqid=%v }", this.Name(), this.type_, this.seq_, this.channel_, this.device_, this.reqid_)
 	} else if this.device_ != nil {
-		return fmt.Sprintf("<%s>{ %v device=%v }", this.Name(), this.type_, this.device_)
+		return fmt.Sprintf("<%s>{ %v seq=%v device=%v }", this.Name(), this.type_, this.seq_, this.device_)
 	} else {
-		return fmt.Sprintf("<%s>{ %v }", this.Name(), this.type_)
+		return fmt.Sprintf("<%s>{ %v seq=%v }", this.Name(), this.type_, this.seq_)
 	}
 }
+
+////////////////////////////////////////////////////////////////////////////////
+// ERROR EVENT IMPLEMENTATION
+
+func (this *casterrorevent) Err() error {
+	return this.err
+}
+
+func (this *casterrorevent) String() string {
+	return fmt.Sprintf("<%s>{ err=%v }", this.Name(), this.err)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// GROUP EVENT IMPLEMENTATION
+
+func (this *castgroupevent) Group() googlecast.Group {
+	if group, ok := this.device_.(googlecast.Group); ok {
+		return group
+	} else {
+		return nil
+	}
+}
+
+func (this *castgroupevent) Members() []googlecast.Device {
+	return this.members_
+}
+
+func (this *castgroupevent) String() string {
+	return fmt.Sprintf("<%s>{ %v group=%v members=%v }", this.Name(), this.type_, this.device_, this.members_)
+}
@@ -0,0 +1,124 @@
+/*
+  Go Language Raspberry Pi Interface
+  (c) Copyright David Thorpe 2019
+  All Rights Reserved
+  Documentation http://djthorpe.github.io/gopi/
+  For Licensing and Usage information, please see LICENSE.md
+*/
+
+package googlecast
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	// Frameworks
+	googlecast "github.com/djthorpe/googlecast"
+	gopi "github.com/djthorpe/gopi"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// castgroup is a cast group, which is discovered in the same way as
+// a device. Membership is tracked through the multizone messages
+// received on a channel connected to the group
+type castgroup struct {
+	*castdevice
+	cast *cast
+}
+
+// multizonedevice is a member of a group, as reported in
+// multizone messages
+type multizonedevice struct {
+	DeviceId     string `json:"deviceId"`
+	Name         string `json:"name"`
+	Capabilities uint   `json:"capabilities"`
+	Volume       volume `json:"volume"`
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// CONSTANTS
+
+const (
+	CAST_MODEL_GROUP = "Google Cast Group"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// IMPLEMENTATION
+
+// Members returns the discovered member devices of the group, or
+// nil if there is no channel connected to the group
+func (this *castgroup) Members() []googlecast.Device {
+	this.castdevice.Mutex.Lock()
+	channel := this.castdevice.channel
+	this.castdevice.Mutex.Unlock()
+	if channel == nil {
+		return nil
+	} else {
+		return this.cast.devicesForIds(channel.MemberIds())
+	}
+}
+
+// Leader returns the device which hosts the group, which is a
+// discovered device with the same address as the group. A member
+// of the group is preferred when several devices share the address
+func (this *castgroup) Leader() googlecast.Device {
+	devices := this.cast.devicesForAddr(this.castdevice)
+	if len(devices) == 0 {
+		return nil
+	}
+	for _, member := range this.Members() {
+		for _, device := range devices {
+			if unwrap(member) == device {
+				return device
+			}
+		}
+	}
+	return devices[0]
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+func (this *castgroup) String() string {
+	members := make([]string, 0)
+	for _, member := range this.Members() {
+		members = append(members, strconv.Quote(member.Name()))
+	}
+	return fmt.Sprintf("<googlecast.Group>{ id=%v name=%v members=%v }", this.Id(), strconv.Quote(this.Name()), strings.Join(members, ","))
+}
+
+func (this multizonedevice) String() string {
+	return fmt.Sprintf("<googlecast.MultizoneDevice>{ id=%v name=%v volume=%v }", this.DeviceId, strconv.Quote(this.Name), &this.Volume)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// isGroup returns true if the device is a cast group. The model name
+// is used, as the capabilities do not reliably indicate a group
+func (this *castdevice) isGroup() bool {
+	return this.Model() == CAST_MODEL_GROUP
+}
+
+// sameAddr returns true if two devices share an address
+func (this *castdevice) sameAddr(device *castdevice) bool {
+	for _, flag := range []gopi.RPCFlag{gopi.RPC_FLAG_INET_V4, gopi.RPC_FLAG_INET_V6} {
+		for _, a := range this.ips(flag) {
+			for _, b := range device.ips(flag) {
+				if a.Equal(b) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// multizoneId returns a device identifier from a multizone message in
+// the same form as the identifier in the service record
+func multizoneId(id string) string {
+	return strings.ToLower(strings.Replace(id, "-", "", -1))
+}
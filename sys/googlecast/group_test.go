@@ -0,0 +1,70 @@
+package googlecast
+
+import (
+	"net"
+	"testing"
+
+	// Frameworks
+	googlecast "github.com/djthorpe/googlecast"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// GROUPS
+
+func TestGroup_000(t *testing.T) {
+	// Groups are identified by model name
+	tests := []struct {
+		text     []string
+		expected bool
+	}{
+		{[]string{"id=a1", "md=" + CAST_MODEL_GROUP, "ca=2084"}, true},
+		{[]string{"id=a2", "md=Google Home", "ca=2084"}, false},
+		{[]string{"id=a3", "md=Chromecast", "ca=4101"}, false},
+		{[]string{"id=a4"}, false},
+	}
+	for _, test := range tests {
+		device := NewDevice(&servicerecord{Service_: SERVICE_TYPE_GOOGLECAST, Text_: test.text})
+		if device.isGroup() != test.expected {
+			t.Errorf("%v: Expected isGroup to return %v", test.text, test.expected)
+		}
+	}
+}
+
+func TestGroupLeader_000(t *testing.T) {
+	this := testCast(t)
+	this.addDevice(testGroupDevice("g1", CAST_MODEL_GROUP, net.IPv4(192, 168, 1, 10), 32187))
+	this.addDevice(testGroupDevice("d1", "Google Home", net.IPv4(192, 168, 1, 11), 8009))
+	group := this.deviceFor(this.device("g1")).(googlecast.Group)
+
+	// No device shares the address of the group
+	if leader := group.Leader(); leader != nil {
+		t.Error("Unexpected leader", leader)
+	}
+
+	// Device with the same address as the group is the leader, although
+	// the group is not connected so members are not known
+	this.addDevice(testGroupDevice("d2", "Google Home", net.IPv4(192, 168, 1, 10), 8009))
+	if leader := group.Leader(); leader == nil {
+		t.Error("Expected leader")
+	} else if leader.Id() != "d2" {
+		t.Error("Unexpected leader", leader)
+	}
+
+	// Another group at the same address is not the leader
+	this.addDevice(testGroupDevice("g2", CAST_MODEL_GROUP, net.IPv4(192, 168, 1, 10), 32188))
+	if leader := group.Leader(); leader == nil || leader.Id() != "d2" {
+		t.Error("Unexpected leader", leader)
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// UTILS
+
+func testGroupDevice(id, model string, ip net.IP, port uint) *castdevice {
+	return NewDevice(&servicerecord{
+		Service_: SERVICE_TYPE_GOOGLECAST,
+		Port_:    port,
+		Text_:    []string{"id=" + id, "md=" + model},
+		IP4_:     []net.IP{ip},
+	})
+}
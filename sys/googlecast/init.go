@@ -9,6 +9,8 @@
 package googlecast
 
 import (
+	"fmt"
+
 	// Frameworks
 	gopi "github.com/djthorpe/gopi"
 )
@@ -22,10 +24,45 @@ func init() {
 		Name:     "googlecast",
 		Type:     gopi.MODULE_TYPE_OTHER,
 		Requires: []string{"discovery"},
+		Config: func(config *gopi.AppConfig) {
+			config.AppFlags.FlagDuration("cast.interval", DELTA_LOOKUP_TIME, "Interval between device lookups")
+			config.AppFlags.FlagDuration("cast.timeout", DELTA_LOOKUP_TIMEOUT, "Timeout for each device lookup")
+			config.AppFlags.FlagDuration("cast.expiry", 0, "Period after which unseen devices are removed (default: five lookup intervals)")
+			config.AppFlags.FlagString("cast.cache", "", "Device cache file or state directory")
+			config.AppFlags.FlagString("cast.allow", "", "Comma-separated rules for devices to allow (id:, name:, model:, subnet:)")
+			config.AppFlags.FlagString("cast.deny", "", "Comma-separated rules for devices to deny (id:, name:, model:, subnet:)")
+			config.AppFlags.FlagBool("cast.manage", false, "Enable device management (reboot, factory reset, rename)")
+			config.AppFlags.FlagUint("cast.history", DEFAULT_EVENT_HISTORY, "Number of events retained for replay")
+			config.AppFlags.FlagDuration("cast.coalesce", 0, "Period over which state events for each device are merged")
+		},
 		New: func(app *gopi.AppInstance) (gopi.Driver, error) {
-			return gopi.Open(Cast{
-				Discovery: app.ModuleInstance("discovery").(gopi.RPCServiceDiscovery),
-			}, app.Logger)
+			interval, _ := app.AppFlags.GetDuration("cast.interval")
+			timeout, _ := app.AppFlags.GetDuration("cast.timeout")
+			expiry, _ := app.AppFlags.GetDuration("cast.expiry")
+			path, _ := app.AppFlags.GetString("cast.cache")
+			allow, _ := app.AppFlags.GetString("cast.allow")
+			deny, _ := app.AppFlags.GetString("cast.deny")
+			manage, _ := app.AppFlags.GetBool("cast.manage")
+			history, _ := app.AppFlags.GetUint("cast.history")
+			coalesce, _ := app.AppFlags.GetDuration("cast.coalesce")
+			if allow_, err := ParseRules(allow); err != nil {
+				return nil, fmt.Errorf("-cast.allow: %w", err)
+			} else if deny_, err := ParseRules(deny); err != nil {
+				return nil, fmt.Errorf("-cast.deny: %w", err)
+			} else {
+				return gopi.Open(Cast{
+					Discovery:      app.ModuleInstance("discovery").(gopi.RPCServiceDiscovery),
+					LookupInterval: interval,
+					LookupTimeout:  timeout,
+					Expiry:         expiry,
+					Path:           path,
+					Allow:          allow_,
+					Deny:           deny_,
+					Manage:         manage,
+					History:        int(history),
+					Coalesce:       coalesce,
+				}, app.Logger)
+			}
 		},
 	})
 }
@@ -9,8 +9,15 @@
 package googlecast
 
 import (
+	"encoding/json"
 	"fmt"
+	"math"
+	"reflect"
 	"strconv"
+	"time"
+
+	// Frameworks
+	googlecast "github.com/djthorpe/googlecast"
 )
 
 ////////////////////////////////////////////////////////////////////////////////
@@ -18,64 +25,244 @@ import (
 
 type media struct {
 	MediaSessionId int       `json:"mediaSessionId"`
-	PlayerState    string    `json:"playerState"`
+	PlayerState_   string    `json:"playerState"`
 	CurrentTime    float32   `json:"currentTime"`
-	IdleReason     string    `json:"idleReason"`
+	IdleReason_    string    `json:"idleReason"`
 	Volume         volume    `json:"volume"`
 	CurrentItemId  int       `json:"currentItemId"`
 	LoadingItemId  int       `json:"loadingItemId"`
 	Media          mediaItem `json:"media"`
+
+	SupportedMediaCommands googlecast.MediaCommand `json:"supportedMediaCommands"`
+	LiveSeekableRange_     *mediaSeekableRange     `json:"liveSeekableRange,omitempty"`
+	ActiveTrackIds_        []int                   `json:"activeTrackIds,omitempty"`
+	PlaybackRate_          float32                 `json:"playbackRate,omitempty"`
+
+	// Time the status was received
+	received time.Time
+}
+
+type mediaSeekableRange struct {
+	Start          float32 `json:"start"`
+	End            float32 `json:"end"`
+	IsMovingWindow bool    `json:"isMovingWindow"`
+	IsLiveDone     bool    `json:"isLiveDone"`
 }
 
 type mediaItem struct {
-	ContentId   string        `json:"contentId"`
-	ContentType string        `json:"contentType"`
-	StreamType  string        `json:"streamType"`
-	Duration    float32       `json:"duration"`
-	Metadata    mediaMetadata `json:"metadata"`
+	ContentId   string         `json:"contentId"`
+	ContentType string         `json:"contentType"`
+	StreamType  string         `json:"streamType,omitempty"`
+	Duration    float32        `json:"duration,omitempty"`
+	Metadata    *mediaMetadata `json:"metadata,omitempty"`
+
+	Tracks_         []mediaTrack    `json:"tracks,omitempty"`
+	TextTrackStyle_ *textTrackStyle `json:"textTrackStyle,omitempty"`
 }
 
+// mediaMetadata is encoded and decoded as one of the metadata types,
+// depending on the metadataType field
 type mediaMetadata struct {
-	MetadataType int          `json:"metadataType`
-	Artist       string       `json:"artist"`
-	Title        string       `json:"title"`
-	Subtitle     string       `json:"subtitle"`
-	Images       []mediaImage `json:"images"`
-	ReleaseDate  string       `json:"releaseDate"`
+	googlecast.MediaMetadata
 }
 
-type mediaImage struct {
-	URL    string `json:"url"`
-	Height int    `json:"height"`
-	Width  int    `json:"width"`
-}
+////////////////////////////////////////////////////////////////////////////////
+// CONSTANTS
+
+const (
+	// Difference in seconds between the reported and estimated
+	// position which is reported as a jump
+	DELTA_POSITION_JUMP = 2.0
+)
 
 ////////////////////////////////////////////////////////////////////////////////
 // IMPLEMENTATION
 
-func (this *media) Equals(other *media) bool {
-	if other == nil {
-		return false
+func (this *media) SupportedCommands() googlecast.MediaCommand {
+	return this.SupportedMediaCommands
+}
+
+func (this *media) Supports(cmd googlecast.MediaCommand) bool {
+	return this.SupportedMediaCommands&cmd == cmd
+}
+
+func (this *media) PlayerState() googlecast.PlayerState {
+	switch this.PlayerState_ {
+	case "IDLE":
+		return googlecast.CAST_PLAYER_STATE_IDLE
+	case "PLAYING":
+		return googlecast.CAST_PLAYER_STATE_PLAYING
+	case "PAUSED":
+		return googlecast.CAST_PLAYER_STATE_PAUSED
+	case "BUFFERING":
+		return googlecast.CAST_PLAYER_STATE_BUFFERING
+	default:
+		return googlecast.CAST_PLAYER_STATE_NONE
 	}
-	if this.MediaSessionId != other.MediaSessionId {
-		return false
+}
+
+func (this *media) IdleReason() string {
+	return this.IdleReason_
+}
+
+func (this *media) ContentId() string {
+	return this.Media.ContentId
+}
+
+func (this *media) ContentType() string {
+	return this.Media.ContentType
+}
+
+func (this *media) Metadata() googlecast.MediaMetadata {
+	if this.Media.Metadata == nil {
+		return nil
+	} else {
+		return this.Media.Metadata.MediaMetadata
 	}
-	if this.PlayerState != other.PlayerState {
+}
+
+func (this *media) Tracks() []googlecast.Track {
+	tracks := make([]googlecast.Track, len(this.Media.Tracks_))
+	for i, track := range this.Media.Tracks_ {
+		tracks[i] = track.Track()
+	}
+	return tracks
+}
+
+func (this *media) ActiveTrackIds() []int {
+	return this.ActiveTrackIds_
+}
+
+func (this *media) TextTrackStyle() *googlecast.TextTrackStyle {
+	return this.Media.TextTrackStyle_.TextTrackStyle()
+}
+
+func (this *media) PlaybackRate() float32 {
+	if this.PlaybackRate_ == 0 {
+		return 1
+	} else {
+		return this.PlaybackRate_
+	}
+}
+
+func (this *media) EstimatedPosition() float32 {
+	return this.estimatedPositionAt(time.Now())
+}
+
+func (this *media) estimatedPositionAt(when time.Time) float32 {
+	if this.PlayerState() != googlecast.CAST_PLAYER_STATE_PLAYING || this.received.IsZero() || when.Before(this.received) {
+		return this.CurrentTime
+	}
+	position := this.CurrentTime + float32(when.Sub(this.received).Seconds())*this.PlaybackRate()
+	if this.StreamType() != googlecast.CAST_STREAM_TYPE_LIVE && this.Media.Duration > 0 && position > this.Media.Duration {
+		return this.Media.Duration
+	} else {
+		return position
+	}
+}
+
+func (this *media) StreamType() googlecast.StreamType {
+	switch this.Media.StreamType {
+	case "BUFFERED":
+		return googlecast.CAST_STREAM_TYPE_BUFFERED
+	case "LIVE":
+		return googlecast.CAST_STREAM_TYPE_LIVE
+	default:
+		return googlecast.CAST_STREAM_TYPE_NONE
+	}
+}
+
+func (this *media) LiveSeekableRange() (float32, float32, bool) {
+	if this.StreamType() != googlecast.CAST_STREAM_TYPE_LIVE || this.LiveSeekableRange_ == nil {
+		return 0, 0, false
+	} else {
+		return this.LiveSeekableRange_.Start, this.LiveSeekableRange_.End, true
+	}
+}
+
+func (this *media) LiveDone() bool {
+	if this.LiveSeekableRange_ == nil {
 		return false
+	} else {
+		return this.LiveSeekableRange_.IsLiveDone
 	}
-	if this.CurrentTime != other.CurrentTime {
+}
+
+// liveEdgeAt returns the end of the live seekable range at a time, which
+// advances in real time after the status was received until the live
+// stream is done
+func (this *media) liveEdgeAt(when time.Time) (float32, bool) {
+	if _, end, ok := this.LiveSeekableRange(); ok == false {
+		return 0, false
+	} else if this.LiveSeekableRange_.IsLiveDone || this.received.IsZero() || when.Before(this.received) {
+		return end, true
+	} else {
+		return end + float32(when.Sub(this.received).Seconds()), true
+	}
+}
+
+func (this *media) BehindLiveEdge() float32 {
+	if _, end, ok := this.LiveSeekableRange(); ok == false {
+		return 0
+	} else if end > this.CurrentTime {
+		return end - this.CurrentTime
+	} else {
+		return 0
+	}
+}
+
+// Changes returns the media events for changes from the previous
+// media status, ending with CAST_EVENT_MEDIA_UPDATED if there are any
+// changes. Progress of the current time during playback is not a change
+func (this *media) Changes(prev *media) []googlecast.EventType {
+	changes := make([]googlecast.EventType, 0, 5)
+	if this == nil && prev == nil {
+		return nil
+	} else if this == nil || prev == nil {
+		changes = append(changes, googlecast.CAST_EVENT_MEDIA_CONTENT_UPDATED)
+	} else {
+		content := this.MediaSessionId != prev.MediaSessionId || this.Media.Equals(prev.Media) == false
+		if content {
+			changes = append(changes, googlecast.CAST_EVENT_MEDIA_CONTENT_UPDATED)
+		}
+		if this.PlayerState_ != prev.PlayerState_ || this.IdleReason_ != prev.IdleReason_ {
+			changes = append(changes, googlecast.CAST_EVENT_MEDIA_PLAYER_STATE_UPDATED)
+		}
+		if this.CurrentItemId != prev.CurrentItemId || this.LoadingItemId != prev.LoadingItemId {
+			changes = append(changes, googlecast.CAST_EVENT_MEDIA_QUEUE_UPDATED)
+		}
+		if reflect.DeepEqual(this.ActiveTrackIds_, prev.ActiveTrackIds_) == false {
+			changes = append(changes, googlecast.CAST_EVENT_MEDIA_TRACKS_UPDATED)
+		}
+		if content == false && math.Abs(float64(this.CurrentTime-prev.estimatedPositionAt(this.received))) > DELTA_POSITION_JUMP {
+			changes = append(changes, googlecast.CAST_EVENT_MEDIA_POSITION_JUMPED)
+		}
+		if len(changes) == 0 && this.equals(prev) == false {
+			changes = append(changes, googlecast.CAST_EVENT_MEDIA_UPDATED)
+		}
+	}
+	if len(changes) > 0 && changes[len(changes)-1] != googlecast.CAST_EVENT_MEDIA_UPDATED {
+		changes = append(changes, googlecast.CAST_EVENT_MEDIA_UPDATED)
+	}
+	return changes
+}
+
+// equals compares properties which are not covered by fine-grained
+// events, ignoring the current time
+func (this *media) equals(other *media) bool {
+	if this.SupportedMediaCommands != other.SupportedMediaCommands {
 		return false
 	}
-	if this.IdleReason != other.IdleReason {
+	if this.LiveSeekableRange_.Equals(other.LiveSeekableRange_) == false {
 		return false
 	}
-	if this.CurrentItemId != other.CurrentItemId {
+	if this.PlaybackRate() != other.PlaybackRate() {
 		return false
 	}
-	if this.LoadingItemId != other.LoadingItemId {
+	if this.Volume.Equals(&other.Volume) == false {
 		return false
 	}
-	return this.Media.Equals(other.Media)
+	return true
 }
 
 func (this *mediaItem) Equals(other mediaItem) bool {
@@ -91,23 +278,118 @@ func (this *mediaItem) Equals(other mediaItem) bool {
 	if this.Duration != other.Duration {
 		return false
 	}
-	return this.Metadata.Equals(other.Metadata)
-}
-
-func (this *mediaMetadata) Equals(other mediaMetadata) bool {
-	if this.MetadataType != other.MetadataType {
+	if reflect.DeepEqual(this.Tracks_, other.Tracks_) == false {
 		return false
 	}
-	if this.Artist != other.Artist {
+	if reflect.DeepEqual(this.TextTrackStyle_, other.TextTrackStyle_) == false {
 		return false
 	}
-	if this.Title != other.Title {
-		return false
+	if this.Metadata == nil || other.Metadata == nil {
+		return this.Metadata == other.Metadata
 	}
-	if this.Subtitle != other.Subtitle {
+	return reflect.DeepEqual(this.Metadata.MediaMetadata, other.Metadata.MediaMetadata)
+}
+
+func (this *mediaSeekableRange) Equals(other *mediaSeekableRange) bool {
+	if this == nil || other == nil {
+		return this == other
+	} else if this.IsMovingWindow != other.IsMovingWindow || this.IsLiveDone != other.IsLiveDone {
 		return false
+	} else if this.IsMovingWindow {
+		// A moving window advances with playback, so only the width matters
+		return this.End-this.Start == other.End-other.Start
+	} else {
+		return *this == *other
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// ENCODE AND DECODE METADATA
+
+func newMediaItem(request googlecast.MediaRequest) mediaItem {
+	item := mediaItem{
+		ContentId:   request.ContentId,
+		ContentType: request.ContentType,
+		Duration:    request.Duration,
+		Metadata:    newMediaMetadata(request.Metadata),
+
+		Tracks_:         newMediaTracks(request.Tracks),
+		TextTrackStyle_: newTextTrackStyle(request.TextTrackStyle),
+	}
+	switch request.StreamType {
+	case googlecast.CAST_STREAM_TYPE_BUFFERED:
+		item.StreamType = "BUFFERED"
+	case googlecast.CAST_STREAM_TYPE_LIVE:
+		item.StreamType = "LIVE"
+	default:
+		item.StreamType = "NONE"
+	}
+	return item
+}
+
+func newMediaMetadata(metadata googlecast.MediaMetadata) *mediaMetadata {
+	if metadata == nil {
+		return nil
+	} else {
+		return &mediaMetadata{metadata}
+	}
+}
+
+func (this *mediaMetadata) UnmarshalJSON(data []byte) error {
+	var header struct {
+		MetadataType googlecast.MetadataType `json:"metadataType"`
+	}
+	if err := json.Unmarshal(data, &header); err != nil {
+		return err
+	}
+	switch header.MetadataType {
+	case googlecast.CAST_METADATA_TYPE_MOVIE:
+		var metadata googlecast.MovieMetadata
+		if err := json.Unmarshal(data, &metadata); err != nil {
+			return err
+		}
+		this.MediaMetadata = metadata
+	case googlecast.CAST_METADATA_TYPE_TV_SHOW:
+		var metadata googlecast.TvShowMetadata
+		if err := json.Unmarshal(data, &metadata); err != nil {
+			return err
+		}
+		this.MediaMetadata = metadata
+	case googlecast.CAST_METADATA_TYPE_MUSIC_TRACK:
+		var metadata googlecast.MusicTrackMetadata
+		if err := json.Unmarshal(data, &metadata); err != nil {
+			return err
+		}
+		this.MediaMetadata = metadata
+	case googlecast.CAST_METADATA_TYPE_PHOTO:
+		var metadata googlecast.PhotoMetadata
+		if err := json.Unmarshal(data, &metadata); err != nil {
+			return err
+		}
+		this.MediaMetadata = metadata
+	default:
+		var metadata googlecast.GenericMetadata
+		if err := json.Unmarshal(data, &metadata); err != nil {
+			return err
+		}
+		this.MediaMetadata = metadata
+	}
+	// Success
+	return nil
+}
+
+func (this *mediaMetadata) MarshalJSON() ([]byte, error) {
+	fields := make(map[string]interface{})
+	if this.MediaMetadata == nil {
+		return []byte("null"), nil
+	} else if data, err := json.Marshal(this.MediaMetadata); err != nil {
+		return nil, err
+	} else if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	} else {
+		fields["metadataType"] = this.MediaMetadata.Type()
+		return json.Marshal(fields)
 	}
-	return true
 }
 
 ////////////////////////////////////////////////////////////////////////////////
@@ -115,21 +397,35 @@ func (this *mediaMetadata) Equals(other mediaMetadata) bool {
 
 func (this *media) String() string {
 	var parts string
-	if this.PlayerState != "" {
-		parts += fmt.Sprintf(" state=%v", strconv.Quote(this.PlayerState))
+	if this.PlayerState_ != "" {
+		parts += fmt.Sprintf(" state=%v", strconv.Quote(this.PlayerState_))
 	}
-	if this.IdleReason != "" {
-		parts += fmt.Sprintf(" idle_reason=%v", strconv.Quote(this.IdleReason))
+	if this.IdleReason_ != "" {
+		parts += fmt.Sprintf(" idle_reason=%v", strconv.Quote(this.IdleReason_))
 	}
-	if this.CurrentTime != 0 {
+	if _, _, ok := this.LiveSeekableRange(); ok {
+		parts += fmt.Sprintf(" behind_live_edge=%v", this.BehindLiveEdge())
+	} else if this.CurrentTime != 0 {
 		parts += fmt.Sprintf(" current_time=%v", this.CurrentTime)
 	}
+	if this.LiveDone() {
+		parts += " live_done=true"
+	}
+	if this.PlaybackRate() != 1 {
+		parts += fmt.Sprintf(" playback_rate=%v", this.PlaybackRate())
+	}
+	if len(this.ActiveTrackIds_) > 0 {
+		parts += fmt.Sprintf(" active_tracks=%v", this.ActiveTrackIds_)
+	}
 	if this.CurrentItemId != 0 {
 		parts += fmt.Sprintf(" current_id=%v", this.CurrentItemId)
 	}
 	if this.LoadingItemId != 0 {
 		parts += fmt.Sprintf(" loading_id=%v", this.LoadingItemId)
 	}
+	if this.SupportedMediaCommands != googlecast.CAST_MEDIA_COMMAND_NONE {
+		parts += fmt.Sprintf(" commands=%v", this.SupportedMediaCommands)
+	}
 	if this.Media.ContentId != "" {
 		parts += fmt.Sprintf(" %v", this.Media)
 	}
@@ -147,39 +443,11 @@ func (this mediaItem) String() string {
 	if this.Duration != 0 {
 		parts += fmt.Sprintf(" duration=%v", this.Duration)
 	}
-	if this.Metadata.MetadataType != 0 {
-		parts += fmt.Sprintf(" %v", this.Metadata)
-	}
-	return fmt.Sprintf("<mediaItem>{ id=%v%v }", this.ContentId, parts)
-}
-
-func (this mediaMetadata) String() string {
-	var parts string
-	if this.Artist != "" {
-		parts += fmt.Sprintf(" artist=%v", strconv.Quote(this.Artist))
-	}
-	if this.Title != "" {
-		parts += fmt.Sprintf(" title=%v", strconv.Quote(this.Title))
-	}
-	if this.Subtitle != "" {
-		parts += fmt.Sprintf(" subtitle=%v", strconv.Quote(this.Subtitle))
-	}
-	if this.ReleaseDate != "" {
-		parts += fmt.Sprintf(" release_date=%v", strconv.Quote(this.ReleaseDate))
+	if len(this.Tracks_) > 0 {
+		parts += fmt.Sprintf(" tracks=%v", len(this.Tracks_))
 	}
-	if len(this.Images) > 0 {
-		parts += fmt.Sprintf(" images=%v", this.Images)
+	if this.Metadata != nil && this.Metadata.MediaMetadata != nil {
+		parts += fmt.Sprintf(" %v", this.Metadata.MediaMetadata)
 	}
-	return fmt.Sprintf("<mediaMetadata>{ type=%v%v }", this.MetadataType, parts)
-}
-
-func (this mediaImage) String() string {
-	var parts string
-	if this.Width != 0 {
-		parts += fmt.Sprintf(" w=%v", this.Width)
-	}
-	if this.Height != 0 {
-		parts += fmt.Sprintf(" h=%v", this.Height)
-	}
-	return fmt.Sprintf("<mediaMetadata>{ url=%v%v }", this.URL, parts)
+	return fmt.Sprintf("<mediaItem>{ id=%v%v }", this.ContentId, parts)
 }
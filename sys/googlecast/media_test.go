@@ -0,0 +1,259 @@
+package googlecast
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	// Frameworks
+	googlecast "github.com/djthorpe/googlecast"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// METADATA
+
+const (
+	MEDIA_STATUS = `{
+		"type": "MEDIA_STATUS",
+		"requestId": 0,
+		"status": [{
+			"mediaSessionId": 1,
+			"playbackRate": 1,
+			"playerState": "PLAYING",
+			"currentTime": 12.5,
+			"supportedMediaCommands": 274447,
+			"volume": { "level": 1, "muted": false },
+			"currentItemId": 1,
+			"repeatMode": "REPEAT_OFF",
+			"media": {
+				"contentId": "http://example.com/media",
+				"contentType": "video/mp4",
+				"streamType": "BUFFERED",
+				"duration": 596.5,
+				"metadata": %s
+			}
+		}]
+	}`
+)
+
+var (
+	mediaMetadataTests = []struct {
+		metadata string
+		expected googlecast.MediaMetadata
+	}{
+		{`{ "metadataType": 0, "title": "Sintel", "subtitle": "Open movie", "images": [{ "url": "http://example.com/sintel.jpg" }] }`, googlecast.GenericMetadata{
+			Title:    "Sintel",
+			Subtitle: "Open movie",
+			Images:   []googlecast.Image{{URL: "http://example.com/sintel.jpg"}},
+		}},
+		{`{ "metadataType": 1, "title": "Big Buck Bunny", "subtitle": "By Blender", "studio": "Blender Foundation", "releaseDate": "2008-05-20" }`, googlecast.MovieMetadata{
+			Title:       "Big Buck Bunny",
+			Subtitle:    "By Blender",
+			Studio:      "Blender Foundation",
+			ReleaseDate: "2008-05-20",
+		}},
+		{`{ "metadataType": 2, "title": "Pilot", "seriesTitle": "Example Show", "season": 1, "episode": 2, "originalAirdate": "2019-01-01" }`, googlecast.TvShowMetadata{
+			Title:           "Pilot",
+			SeriesTitle:     "Example Show",
+			Season:          1,
+			Episode:         2,
+			OriginalAirdate: "2019-01-01",
+		}},
+		{`{ "metadataType": 3, "title": "Track", "albumName": "Album", "albumArtist": "Band", "artist": "Singer", "composer": "Writer", "trackNumber": 4, "discNumber": 1, "images": [{ "url": "http://example.com/cover.jpg", "width": 300, "height": 300 }] }`, googlecast.MusicTrackMetadata{
+			Title:       "Track",
+			AlbumName:   "Album",
+			AlbumArtist: "Band",
+			Artist:      "Singer",
+			Composer:    "Writer",
+			TrackNumber: 4,
+			DiscNumber:  1,
+			Images:      []googlecast.Image{{URL: "http://example.com/cover.jpg", Width: 300, Height: 300}},
+		}},
+		{`{ "metadataType": 4, "title": "Harbour", "artist": "Photographer", "location": "Sydney", "latitude": -33.8523, "longitude": 151.2108, "width": 1920, "height": 1080, "creationDateTime": "2019-06-01T12:00:00Z" }`, googlecast.PhotoMetadata{
+			Title:            "Harbour",
+			Artist:           "Photographer",
+			Location:         "Sydney",
+			Latitude:         -33.8523,
+			Longitude:        151.2108,
+			Width:            1920,
+			Height:           1080,
+			CreationDateTime: "2019-06-01T12:00:00Z",
+		}},
+	}
+)
+
+func TestMediaMetadata_000(t *testing.T) {
+	// Decode metadata from media status for each metadata type
+	for _, test := range mediaMetadataTests {
+		var status MediaStatusResponse
+		if err := json.Unmarshal([]byte(fmt.Sprintf(MEDIA_STATUS, test.metadata)), &status); err != nil {
+			t.Fatal(err)
+		} else if len(status.Status) != 1 {
+			t.Fatal("Unexpected status", status)
+		} else if metadata := status.Status[0].Metadata(); metadata == nil {
+			t.Error("Missing metadata for", test.expected.Type())
+		} else if metadata.Type() != test.expected.Type() {
+			t.Error("Unexpected metadata type", metadata.Type(), "expected", test.expected.Type())
+		} else if reflect.DeepEqual(metadata, test.expected) == false {
+			t.Errorf("Unexpected metadata %v, expected %v", metadata, test.expected)
+		}
+	}
+}
+
+func TestMediaMetadata_001(t *testing.T) {
+	// Encode metadata for each metadata type, and decode it again
+	for _, test := range mediaMetadataTests {
+		var decoded mediaMetadata
+		if data, err := json.Marshal(newMediaMetadata(test.expected)); err != nil {
+			t.Fatal(err)
+		} else if err := json.Unmarshal(data, &decoded); err != nil {
+			t.Fatal(err)
+		} else if reflect.DeepEqual(decoded.MediaMetadata, test.expected) == false {
+			t.Errorf("Unexpected metadata %v, expected %v (%v)", decoded.MediaMetadata, test.expected, string(data))
+		}
+	}
+}
+
+func TestMediaMetadata_002(t *testing.T) {
+	// Media status without metadata
+	var status MediaStatusResponse
+	if err := json.Unmarshal([]byte(fmt.Sprintf(MEDIA_STATUS, "null")), &status); err != nil {
+		t.Fatal(err)
+	} else if metadata := status.Status[0].Metadata(); metadata != nil {
+		t.Error("Unexpected metadata", metadata)
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// CHANGES
+
+func TestMediaChanges_000(t *testing.T) {
+	now := time.Now()
+	status := func(fn func(*media)) *media {
+		value := &media{
+			MediaSessionId: 1,
+			PlayerState_:   "PAUSED",
+			CurrentTime:    10,
+			CurrentItemId:  1,
+			Media:          mediaItem{ContentId: "http://example.com/media", ContentType: "video/mp4", StreamType: "BUFFERED", Duration: 100},
+			received:       now,
+		}
+		if fn != nil {
+			fn(value)
+		}
+		return value
+	}
+	tests := []struct {
+		name     string
+		prev     *media
+		value    *media
+		expected []googlecast.EventType
+	}{
+		{"unchanged", status(nil), status(nil), []googlecast.EventType{}},
+		{"added", nil, status(nil), []googlecast.EventType{
+			googlecast.CAST_EVENT_MEDIA_CONTENT_UPDATED,
+			googlecast.CAST_EVENT_MEDIA_UPDATED,
+		}},
+		{"removed", status(nil), nil, []googlecast.EventType{
+			googlecast.CAST_EVENT_MEDIA_CONTENT_UPDATED,
+			googlecast.CAST_EVENT_MEDIA_UPDATED,
+		}},
+		{"content", status(nil), status(func(m *media) { m.Media.ContentId = "http://example.com/other" }), []googlecast.EventType{
+			googlecast.CAST_EVENT_MEDIA_CONTENT_UPDATED,
+			googlecast.CAST_EVENT_MEDIA_UPDATED,
+		}},
+		{"session", status(nil), status(func(m *media) { m.MediaSessionId = 2 }), []googlecast.EventType{
+			googlecast.CAST_EVENT_MEDIA_CONTENT_UPDATED,
+			googlecast.CAST_EVENT_MEDIA_UPDATED,
+		}},
+		{"player_state", status(nil), status(func(m *media) { m.PlayerState_ = "BUFFERING" }), []googlecast.EventType{
+			googlecast.CAST_EVENT_MEDIA_PLAYER_STATE_UPDATED,
+			googlecast.CAST_EVENT_MEDIA_UPDATED,
+		}},
+		{"idle_reason", status(nil), status(func(m *media) { m.PlayerState_ = "IDLE"; m.IdleReason_ = "FINISHED" }), []googlecast.EventType{
+			googlecast.CAST_EVENT_MEDIA_PLAYER_STATE_UPDATED,
+			googlecast.CAST_EVENT_MEDIA_UPDATED,
+		}},
+		{"queue", status(nil), status(func(m *media) { m.CurrentItemId = 2 }), []googlecast.EventType{
+			googlecast.CAST_EVENT_MEDIA_QUEUE_UPDATED,
+			googlecast.CAST_EVENT_MEDIA_UPDATED,
+		}},
+		{"tracks", status(nil), status(func(m *media) { m.ActiveTrackIds_ = []int{1} }), []googlecast.EventType{
+			googlecast.CAST_EVENT_MEDIA_TRACKS_UPDATED,
+			googlecast.CAST_EVENT_MEDIA_UPDATED,
+		}},
+		{"position_jumped", status(nil), status(func(m *media) { m.CurrentTime = 60 }), []googlecast.EventType{
+			googlecast.CAST_EVENT_MEDIA_POSITION_JUMPED,
+			googlecast.CAST_EVENT_MEDIA_UPDATED,
+		}},
+		{"progress", status(func(m *media) { m.PlayerState_ = "PLAYING" }), status(func(m *media) {
+			m.PlayerState_ = "PLAYING"
+			m.CurrentTime = 15
+			m.received = now.Add(5 * time.Second)
+		}), []googlecast.EventType{}},
+		{"live_range", status(func(m *media) {
+			m.LiveSeekableRange_ = &mediaSeekableRange{Start: 0, End: 100}
+		}), status(func(m *media) {
+			m.LiveSeekableRange_ = &mediaSeekableRange{Start: 0, End: 100, IsLiveDone: true}
+		}), []googlecast.EventType{
+			googlecast.CAST_EVENT_MEDIA_UPDATED,
+		}},
+		{"live_range_window", status(func(m *media) {
+			m.LiveSeekableRange_ = &mediaSeekableRange{Start: 0, End: 100, IsMovingWindow: true}
+		}), status(func(m *media) {
+			m.LiveSeekableRange_ = &mediaSeekableRange{Start: 10, End: 110, IsMovingWindow: true}
+		}), []googlecast.EventType{}},
+		{"live_range_window_width", status(func(m *media) {
+			m.LiveSeekableRange_ = &mediaSeekableRange{Start: 0, End: 100, IsMovingWindow: true}
+		}), status(func(m *media) {
+			m.LiveSeekableRange_ = &mediaSeekableRange{Start: 0, End: 120, IsMovingWindow: true}
+		}), []googlecast.EventType{
+			googlecast.CAST_EVENT_MEDIA_UPDATED,
+		}},
+		{"volume", status(nil), status(func(m *media) { m.Volume.Muted_ = true }), []googlecast.EventType{
+			googlecast.CAST_EVENT_MEDIA_UPDATED,
+		}},
+		{"commands", status(nil), status(func(m *media) { m.SupportedMediaCommands = googlecast.CAST_MEDIA_COMMAND_PAUSE }), []googlecast.EventType{
+			googlecast.CAST_EVENT_MEDIA_UPDATED,
+		}},
+	}
+	for _, test := range tests {
+		changes := test.value.Changes(test.prev)
+		if len(changes) == 0 && len(test.expected) == 0 {
+			continue
+		} else if reflect.DeepEqual(changes, test.expected) == false {
+			t.Errorf("%v: Unexpected changes %v, expected %v", test.name, changes, test.expected)
+		} else if changes[len(changes)-1] != googlecast.CAST_EVENT_MEDIA_UPDATED {
+			t.Errorf("%v: Expected changes to end with CAST_EVENT_MEDIA_UPDATED", test.name)
+		}
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// LIVE EDGE
+
+func TestMediaLiveEdge_000(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name     string
+		value    *media
+		expected float32
+		ok       bool
+	}{
+		{"buffered", &media{Media: mediaItem{StreamType: "BUFFERED"}, received: now}, 0, false},
+		{"live_no_range", &media{Media: mediaItem{StreamType: "LIVE"}, received: now}, 0, false},
+		{"live", &media{Media: mediaItem{StreamType: "LIVE"}, LiveSeekableRange_: &mediaSeekableRange{Start: 0, End: 100}, received: now.Add(-10 * time.Second)}, 110, true},
+		{"live_paused", &media{PlayerState_: "PAUSED", Media: mediaItem{StreamType: "LIVE"}, LiveSeekableRange_: &mediaSeekableRange{Start: 0, End: 100}, received: now.Add(-10 * time.Second)}, 110, true},
+		{"live_done", &media{Media: mediaItem{StreamType: "LIVE"}, LiveSeekableRange_: &mediaSeekableRange{Start: 0, End: 100, IsLiveDone: true}, received: now.Add(-10 * time.Second)}, 100, true},
+		{"live_not_received", &media{Media: mediaItem{StreamType: "LIVE"}, LiveSeekableRange_: &mediaSeekableRange{Start: 0, End: 100}}, 100, true},
+	}
+	for _, test := range tests {
+		if end, ok := test.value.liveEdgeAt(now); ok != test.ok {
+			t.Errorf("%v: Unexpected ok %v", test.name, ok)
+		} else if end != test.expected {
+			t.Errorf("%v: Unexpected live edge %v, expected %v", test.name, end, test.expected)
+		}
+	}
+}
@@ -18,47 +18,119 @@ type Payload interface {
 }
 
 type PayloadHeader struct {
-	Type      string `json:"type"`
-	RequestId int    `json:"requestId,omitempty"`
+	Type         string `json:"type,omitempty"`
+	ResponseType string `json:"responseType,omitempty"`
+	RequestId    int    `json:"requestId,omitempty"`
+}
+
+type AppAvailabilityRequest struct {
+	PayloadHeader
+	AppId []string `json:"appId"`
+}
+
+type AppAvailabilityResponse struct {
+	PayloadHeader
+	Availability map[string]string `json:"availability"`
 }
 
 type ReceiverStatusResponse struct {
 	PayloadHeader
 	Status struct {
-		Applications []application `json:"applications"`
-		Volume       volume        `json:"volume"`
+		Applications  []application `json:"applications"`
+		Volume        volume        `json:"volume"`
+		IsActiveInput *bool         `json:"isActiveInput"`
+		IsStandBy     *bool         `json:"isStandBy"`
 	} `json:"status"`
 }
 
+type LaunchErrorResponse struct {
+	PayloadHeader
+	Reason string `json:"reason"`
+}
+
 type MediaStatusResponse struct {
 	PayloadHeader
 	Status []media `json:"status"`
 }
 
-/*
-type MediaHeader struct {
+type MultizoneStatusResponse struct {
 	PayloadHeader
-	MediaSessionId int     `json:"mediaSessionId"`
-	CurrentTime    float32 `json:"currentTime"`
-	RelativeTime   float32 `json:"relativeTime,omitempty"`
-	ResumeState    string  `json:"resumeState"`
+	Status struct {
+		Devices        []multizonedevice `json:"devices"`
+		IsMultichannel bool              `json:"isMultichannel"`
+	} `json:"status"`
 }
 
-type VolumeHeader struct {
+type MultizoneDeviceResponse struct {
 	PayloadHeader
-	Volume volume `json:"volume"`
+	Device   multizonedevice `json:"device"`
+	DeviceId string          `json:"deviceId"`
 }
 
-type DeviceUpdatedResponse struct {
+type LaunchRequest struct {
 	PayloadHeader
-	Device struct {
-		DeviceId string `json:"deviceId"`
-		Name     string `json:"name"`
-		Volume   volume `json:"volume"`
-	} `json:"device"`
+	AppId string `json:"appId"`
 }
 
-*/
+type StopRequest struct {
+	PayloadHeader
+	SessionId string `json:"sessionId"`
+}
+
+type ReceiverVolumeRequest struct {
+	PayloadHeader
+	Volume VolumeRequest `json:"volume"`
+}
+
+type MediaHeader struct {
+	PayloadHeader
+	MediaSessionId int `json:"mediaSessionId"`
+}
+
+type MediaLoadRequest struct {
+	PayloadHeader
+	Media          mediaItem `json:"media"`
+	Autoplay       bool      `json:"autoplay"`
+	CurrentTime    float32   `json:"currentTime"`
+	ActiveTrackIds []int     `json:"activeTrackIds,omitempty"`
+}
+
+type MediaTracksRequest struct {
+	MediaHeader
+	ActiveTrackIds *[]int          `json:"activeTrackIds,omitempty"`
+	TextTrackStyle *textTrackStyle `json:"textTrackStyle,omitempty"`
+}
+
+type MediaSeekRequest struct {
+	MediaHeader
+	CurrentTime float32 `json:"currentTime"`
+}
+
+type MediaSkipRequest struct {
+	MediaHeader
+	RelativeTime float32 `json:"relativeTime"`
+}
+
+type MediaPlaybackRateRequest struct {
+	MediaHeader
+	PlaybackRate float32 `json:"playbackRate"`
+}
+
+type MediaQueueRequest struct {
+	MediaHeader
+	Jump int `json:"jump"`
+}
+
+type MediaVolumeRequest struct {
+	MediaHeader
+	Volume VolumeRequest `json:"volume"`
+}
+
+// VolumeRequest sets either the level or muted state
+type VolumeRequest struct {
+	Level *float32 `json:"level,omitempty"`
+	Muted *bool    `json:"muted,omitempty"`
+}
 
 ////////////////////////////////////////////////////////////////////////////////
 // GLOBAL VARIABLES
@@ -80,14 +152,62 @@ func (this *PayloadHeader) WithId(id int) Payload {
 	return this
 }
 
-/*
+func (this *AppAvailabilityRequest) WithId(id int) Payload {
+	this.PayloadHeader.RequestId = id
+	return this
+}
+
+func (this *LaunchRequest) WithId(id int) Payload {
+	this.PayloadHeader.RequestId = id
+	return this
+}
+
+func (this *StopRequest) WithId(id int) Payload {
+	this.PayloadHeader.RequestId = id
+	return this
+}
+
+func (this *ReceiverVolumeRequest) WithId(id int) Payload {
+	this.PayloadHeader.RequestId = id
+	return this
+}
+
 func (this *MediaHeader) WithId(id int) Payload {
 	this.PayloadHeader.RequestId = id
 	return this
 }
 
-func (this *VolumeHeader) WithId(id int) Payload {
+func (this *MediaLoadRequest) WithId(id int) Payload {
+	this.PayloadHeader.RequestId = id
+	return this
+}
+
+func (this *MediaTracksRequest) WithId(id int) Payload {
+	this.PayloadHeader.RequestId = id
+	return this
+}
+
+func (this *MediaSeekRequest) WithId(id int) Payload {
+	this.PayloadHeader.RequestId = id
+	return this
+}
+
+func (this *MediaSkipRequest) WithId(id int) Payload {
+	this.PayloadHeader.RequestId = id
+	return this
+}
+
+func (this *MediaPlaybackRateRequest) WithId(id int) Payload {
+	this.PayloadHeader.RequestId = id
+	return this
+}
+
+func (this *MediaQueueRequest) WithId(id int) Payload {
+	this.PayloadHeader.RequestId = id
+	return this
+}
+
+func (this *MediaVolumeRequest) WithId(id int) Payload {
 	this.PayloadHeader.RequestId = id
 	return this
 }
-*/
@@ -0,0 +1,148 @@
+/*
+  Go Language Raspberry Pi Interface
+  (c) Copyright David Thorpe 2019
+  All Rights Reserved
+  Documentation http://djthorpe.github.io/gopi/
+  For Licensing and Usage information, please see LICENSE.md
+*/
+
+package googlecast
+
+import (
+	"fmt"
+	"net"
+	"path"
+	"strconv"
+	"strings"
+
+	// Frameworks
+	googlecast "github.com/djthorpe/googlecast"
+	gopi "github.com/djthorpe/gopi"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// Rule matches devices on identifier, name or glob pattern, model and
+// capabilities, and on the subnet of any device address. All non-empty
+// fields need to match for the rule to match
+type Rule struct {
+	googlecast.DeviceQuery
+	Subnet *net.IPNet
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PARSE RULES
+
+// ParseRules parses a comma-separated list of rules, where each rule
+// is of the form id:<id>, name:<glob>, model:<model> or subnet:<cidr>
+func ParseRules(value string) ([]Rule, error) {
+	rules := make([]Rule, 0)
+	for _, term := range strings.Split(value, ",") {
+		if term = strings.TrimSpace(term); term == "" {
+			continue
+		} else if rule, err := ParseRule(term); err != nil {
+			return nil, err
+		} else {
+			rules = append(rules, rule)
+		}
+	}
+	return rules, nil
+}
+
+// ParseRule parses a single rule of the form <key>:<value>
+func ParseRule(term string) (Rule, error) {
+	var rule Rule
+	if pair := strings.SplitN(term, ":", 2); len(pair) != 2 || pair[1] == "" {
+		return rule, fmt.Errorf("Invalid rule: %v", strconv.Quote(term))
+	} else {
+		switch strings.ToLower(strings.TrimSpace(pair[0])) {
+		case "id":
+			rule.Id = pair[1]
+		case "name":
+			if _, err := path.Match(pair[1], ""); err != nil {
+				return rule, fmt.Errorf("Invalid rule: %v: %w", strconv.Quote(term), err)
+			} else {
+				rule.Name = pair[1]
+			}
+		case "model":
+			rule.Model = pair[1]
+		case "subnet":
+			if _, subnet, err := net.ParseCIDR(pair[1]); err != nil {
+				return rule, fmt.Errorf("Invalid rule: %v: %w", strconv.Quote(term), err)
+			} else {
+				rule.Subnet = subnet
+			}
+		default:
+			return rule, fmt.Errorf("Invalid rule: %v", strconv.Quote(term))
+		}
+	}
+	return rule, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// MATCH RULES
+
+// Matches returns true if the device matches the rule
+func (this Rule) Matches(device *castdevice) bool {
+	if device.Matches(this.DeviceQuery) == false {
+		return false
+	}
+	if this.Subnet != nil {
+		for _, flag := range []gopi.RPCFlag{gopi.RPC_FLAG_INET_V4, gopi.RPC_FLAG_INET_V6} {
+			for _, ip := range device.ips(flag) {
+				if this.Subnet.Contains(ip) {
+					return true
+				}
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// allowed returns true if the device matches any allow rule (or there
+// are no allow rules) and does not match any deny rule
+func (this *cast) allowed(device *castdevice) bool {
+	if len(this.allow) > 0 {
+		allowed := false
+		for _, rule := range this.allow {
+			if rule.Matches(device) {
+				allowed = true
+				break
+			}
+		}
+		if allowed == false {
+			return false
+		}
+	}
+	for _, rule := range this.deny {
+		if rule.Matches(device) {
+			return false
+		}
+	}
+	return true
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+func (this Rule) String() string {
+	parts := ""
+	if this.Id != "" {
+		parts += fmt.Sprintf(" id=%v", strconv.Quote(this.Id))
+	}
+	if this.Name != "" {
+		parts += fmt.Sprintf(" name=%v", strconv.Quote(this.Name))
+	}
+	if this.Model != "" {
+		parts += fmt.Sprintf(" model=%v", strconv.Quote(this.Model))
+	}
+	if this.Capabilities != googlecast.CAST_CAPABILITY_NONE {
+		parts += fmt.Sprintf(" capabilities=%v", this.Capabilities)
+	}
+	if this.Subnet != nil {
+		parts += fmt.Sprintf(" subnet=%v", this.Subnet)
+	}
+	return fmt.Sprintf("<googlecast.Rule>{%v }", parts)
+}
@@ -0,0 +1,107 @@
+package googlecast
+
+import (
+	"net"
+	"testing"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// PARSE RULES
+
+func TestParseRules_000(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected []string
+	}{
+		{"", []string{}},
+		{" , ", []string{}},
+		{"id:a1b2c3", []string{`<googlecast.Rule>{ id="a1b2c3" }`}},
+		{"name:Kitchen*", []string{`<googlecast.Rule>{ name="Kitchen*" }`}},
+		{"NAME:Living room", []string{`<googlecast.Rule>{ name="Living room" }`}},
+		{"model:Chromecast, subnet:192.168.1.0/24", []string{`<googlecast.Rule>{ model="Chromecast" }`, `<googlecast.Rule>{ subnet=192.168.1.0/24 }`}},
+		{"name:a:b", []string{`<googlecast.Rule>{ name="a:b" }`}},
+	}
+	for _, test := range tests {
+		if rules, err := ParseRules(test.value); err != nil {
+			t.Errorf("%q: %v", test.value, err)
+		} else if len(rules) != len(test.expected) {
+			t.Errorf("%q: Unexpected rules %v", test.value, rules)
+		} else {
+			for i, rule := range rules {
+				if rule.String() != test.expected[i] {
+					t.Errorf("%q: Unexpected rule %v, expected %v", test.value, rule, test.expected[i])
+				}
+			}
+		}
+	}
+}
+
+func TestParseRules_001(t *testing.T) {
+	// Invalid rules and patterns
+	for _, value := range []string{
+		"a1b2c3",
+		"id:",
+		"colour:red",
+		"name:[",
+		"name:Kitchen[a-",
+		"subnet:192.168.1.0",
+		"subnet:192.168.1.0/33",
+		"id:a1b2c3,model",
+	} {
+		if rules, err := ParseRules(value); err == nil {
+			t.Errorf("%q: Expected error, got %v", value, rules)
+		}
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// MATCH RULES
+
+func TestRulesAllowed_000(t *testing.T) {
+	kitchen := testRuleDevice("a1", "Kitchen speaker", "Google Home", net.IPv4(192, 168, 1, 20))
+	lounge := testRuleDevice("b2", "Living room TV", "Chromecast", net.IPv4(192, 168, 2, 30))
+
+	tests := []struct {
+		name     string
+		allow    string
+		deny     string
+		expected []bool
+	}{
+		{"empty", "", "", []bool{true, true}},
+		{"allow_id", "id:a1", "", []bool{true, false}},
+		{"allow_glob", "name:*speaker", "", []bool{true, false}},
+		{"allow_glob_none", "name:Bedroom*", "", []bool{false, false}},
+		{"allow_any", "name:Kitchen*,model:Chromecast", "", []bool{true, true}},
+		{"allow_subnet", "subnet:192.168.2.0/24", "", []bool{false, true}},
+		{"deny_model", "", "model:Chromecast", []bool{true, false}},
+		{"deny_glob", "", "name:*", []bool{false, false}},
+		{"deny_precedence", "name:*", "id:a1", []bool{false, true}},
+		{"deny_precedence_subnet", "subnet:192.168.0.0/16", "subnet:192.168.1.0/24", []bool{false, true}},
+	}
+	for _, test := range tests {
+		this := testCast(t)
+		if allow, err := ParseRules(test.allow); err != nil {
+			t.Fatal(err)
+		} else if deny, err := ParseRules(test.deny); err != nil {
+			t.Fatal(err)
+		} else {
+			this.allow, this.deny = allow, deny
+		}
+		for i, device := range []*castdevice{kitchen, lounge} {
+			if allowed := this.allowed(device); allowed != test.expected[i] {
+				t.Errorf("%v: Expected allowed to return %v for %v", test.name, test.expected[i], device.Name())
+			}
+		}
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// UTILS
+
+func testRuleDevice(id, name, model string, ip net.IP) *castdevice {
+	return NewDevice(&servicerecord{
+		Service_: SERVICE_TYPE_GOOGLECAST,
+		Text_:    []string{"id=" + id, "fn=" + name, "md=" + model},
+		IP4_:     []net.IP{ip},
+	})
+}
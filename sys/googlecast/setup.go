@@ -0,0 +1,351 @@
+/*
+  Go Language Raspberry Pi Interface
+  (c) Copyright David Thorpe 2019
+  All Rights Reserved
+  Documentation http://djthorpe.github.io/gopi/
+  For Licensing and Usage information, please see LICENSE.md
+*/
+
+package googlecast
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	// Frameworks
+	googlecast "github.com/djthorpe/googlecast"
+	gopi "github.com/djthorpe/gopi"
+)
+
+// Ref: https://rithvikvibhu.github.io/GHLocalApi/
+//
+// Requests are sent to the unauthenticated API on port 8008. Current
+// firmware only answers many requests on port 8443 with a local
+// authorization token obtained through a Google account, which is
+// not supported, so management and info may fail with those devices
+
+////////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+type setupReboot struct {
+	Params string `json:"params"`
+}
+
+type setupEurekaInfo struct {
+	Name     string `json:"name,omitempty"`
+	Timezone string `json:"timezone,omitempty"`
+	Locale   string `json:"locale,omitempty"`
+}
+
+// deviceinfo is decoded from the detailed response, where settings are
+// nested, or from the flat response returned by older firmware
+type deviceinfo struct {
+	Name_              string              `json:"name"`
+	Version_           int                 `json:"version"`
+	BuildVersion_      string              `json:"build_version"`
+	CastBuildRevision_ string              `json:"cast_build_revision"`
+	Uptime_            float64             `json:"uptime"`
+	MacAddress_        string              `json:"mac_address"`
+	Timezone_          string              `json:"timezone"`
+	Locale_            string              `json:"locale"`
+	IPAddress_         string              `json:"ip_address"`
+	EthernetConnected_ bool                `json:"ethernet_connected"`
+	SSID_              string              `json:"ssid"`
+	BSSID_             string              `json:"bssid"`
+	SignalLevel_       int                 `json:"signal_level"`
+	NoiseLevel_        int                 `json:"noise_level"`
+	BuildInfo_         *deviceinfoBuild    `json:"build_info"`
+	DeviceInfo_        *deviceinfoDevice   `json:"device_info"`
+	Net_               *deviceinfoNet      `json:"net"`
+	Wifi_              *deviceinfoWifi     `json:"wifi"`
+	Settings_          *deviceinfoSettings `json:"settings"`
+}
+
+type deviceinfoBuild struct {
+	CastBuildRevision string `json:"cast_build_revision"`
+	SystemBuildNumber string `json:"system_build_number"`
+}
+
+type deviceinfoDevice struct {
+	MacAddress string  `json:"mac_address"`
+	Uptime     float64 `json:"uptime"`
+}
+
+type deviceinfoNet struct {
+	IPAddress         string `json:"ip_address"`
+	EthernetConnected bool   `json:"ethernet_connected"`
+}
+
+type deviceinfoWifi struct {
+	SSID        string `json:"ssid"`
+	BSSID       string `json:"bssid"`
+	SignalLevel int    `json:"signal_level"`
+	NoiseLevel  int    `json:"noise_level"`
+}
+
+type deviceinfoSettings struct {
+	Timezone string `json:"timezone"`
+	Locale   string `json:"locale"`
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// CONSTANTS
+
+const (
+	SETUP_PORT          = 8008
+	SETUP_PATH_INFO     = "/setup/eureka_info"
+	SETUP_QUERY_INFO    = "?params=version,name,build_info,device_info,net,wifi,settings&options=detail"
+	SETUP_PATH_SET_INFO = "/setup/set_eureka_info"
+	SETUP_PATH_REBOOT   = "/setup/reboot"
+	SETUP_REBOOT_NOW    = "now"
+	SETUP_REBOOT_FDR    = "fdr"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// DEVICE INFO
+
+// Info returns information from the setup API of the device
+func (this *castdevice) Info(ctx context.Context) (googlecast.DeviceInfo, error) {
+	if url, err := this.setupURL(SETUP_PATH_INFO + SETUP_QUERY_INFO); err != nil {
+		return nil, err
+	} else {
+		return getDeviceInfo(ctx, url)
+	}
+}
+
+func getDeviceInfo(ctx context.Context, url string) (*deviceinfo, error) {
+	info := new(deviceinfo)
+	if err := setupRequest(ctx, http.MethodGet, url, nil, info); err != nil {
+		return nil, err
+	} else {
+		return info, nil
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// DEVICE MANAGEMENT
+
+func (this *cast) Reboot(ctx context.Context, device googlecast.Device) error {
+	this.log.Debug2("<googlecast.Reboot>{ device=%v }", device)
+	return this.setup(ctx, device, SETUP_PATH_REBOOT, &setupReboot{SETUP_REBOOT_NOW})
+}
+
+func (this *cast) FactoryReset(ctx context.Context, device googlecast.Device, confirm string) error {
+	this.log.Debug2("<googlecast.FactoryReset>{ device=%v }", device)
+	if device == nil || confirm != device.Id() {
+		return gopi.ErrBadParameter
+	} else {
+		return this.setup(ctx, device, SETUP_PATH_REBOOT, &setupReboot{SETUP_REBOOT_FDR})
+	}
+}
+
+func (this *cast) SetName(ctx context.Context, device googlecast.Device, name string) error {
+	this.log.Debug2("<googlecast.SetName>{ device=%v name=%v }", device, strconv.Quote(name))
+	if name == "" {
+		return gopi.ErrBadParameter
+	} else {
+		return this.setup(ctx, device, SETUP_PATH_SET_INFO, &setupEurekaInfo{Name: name})
+	}
+}
+
+func (this *cast) SetTimezone(ctx context.Context, device googlecast.Device, timezone string) error {
+	this.log.Debug2("<googlecast.SetTimezone>{ device=%v timezone=%v }", device, strconv.Quote(timezone))
+	if _, err := time.LoadLocation(timezone); timezone == "" || err != nil {
+		return gopi.ErrBadParameter
+	} else {
+		return this.setup(ctx, device, SETUP_PATH_SET_INFO, &setupEurekaInfo{Timezone: timezone})
+	}
+}
+
+func (this *cast) SetLocale(ctx context.Context, device googlecast.Device, locale string) error {
+	this.log.Debug2("<googlecast.SetLocale>{ device=%v locale=%v }", device, strconv.Quote(locale))
+	if locale == "" {
+		return gopi.ErrBadParameter
+	} else {
+		return this.setup(ctx, device, SETUP_PATH_SET_INFO, &setupEurekaInfo{Locale: locale})
+	}
+}
+
+// setup sends a management request to the setup API of a device, when
+// management has been enabled
+func (this *cast) setup(ctx context.Context, device googlecast.Device, path string, request interface{}) error {
+	if this.manage == false {
+		return googlecast.ErrManagementDisabled
+	} else if device_ := unwrap(device); device_ == nil {
+		return gopi.ErrBadParameter
+	} else if url, err := device_.setupURL(path); err != nil {
+		return err
+	} else {
+		return setupRequest(ctx, http.MethodPost, url, request, nil)
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// DEVICE INFO IMPLEMENTATION
+
+func (this *deviceinfo) Name() string {
+	return this.Name_
+}
+
+func (this *deviceinfo) Version() string {
+	return fmt.Sprint(this.Version_)
+}
+
+func (this *deviceinfo) CastBuildRevision() string {
+	if this.BuildInfo_ != nil && this.BuildInfo_.CastBuildRevision != "" {
+		return this.BuildInfo_.CastBuildRevision
+	} else {
+		return this.CastBuildRevision_
+	}
+}
+
+func (this *deviceinfo) BuildVersion() string {
+	if this.BuildInfo_ != nil && this.BuildInfo_.SystemBuildNumber != "" {
+		return this.BuildInfo_.SystemBuildNumber
+	} else {
+		return this.BuildVersion_
+	}
+}
+
+func (this *deviceinfo) Uptime() time.Duration {
+	uptime := this.Uptime_
+	if this.DeviceInfo_ != nil && this.DeviceInfo_.Uptime != 0 {
+		uptime = this.DeviceInfo_.Uptime
+	}
+	return time.Duration(uptime * float64(time.Second))
+}
+
+func (this *deviceinfo) MacAddress() string {
+	if this.DeviceInfo_ != nil && this.DeviceInfo_.MacAddress != "" {
+		return this.DeviceInfo_.MacAddress
+	} else {
+		return this.MacAddress_
+	}
+}
+
+func (this *deviceinfo) Timezone() string {
+	if this.Settings_ != nil && this.Settings_.Timezone != "" {
+		return this.Settings_.Timezone
+	} else {
+		return this.Timezone_
+	}
+}
+
+func (this *deviceinfo) Locale() string {
+	if this.Settings_ != nil && this.Settings_.Locale != "" {
+		return this.Settings_.Locale
+	} else {
+		return this.Locale_
+	}
+}
+
+func (this *deviceinfo) IPAddress() net.IP {
+	if this.Net_ != nil && this.Net_.IPAddress != "" {
+		return net.ParseIP(this.Net_.IPAddress)
+	} else {
+		return net.ParseIP(this.IPAddress_)
+	}
+}
+
+func (this *deviceinfo) EthernetConnected() bool {
+	if this.Net_ != nil {
+		return this.Net_.EthernetConnected
+	} else {
+		return this.EthernetConnected_
+	}
+}
+
+func (this *deviceinfo) SSID() string {
+	if this.Wifi_ != nil {
+		return this.Wifi_.SSID
+	} else {
+		return this.SSID_
+	}
+}
+
+func (this *deviceinfo) BSSID() string {
+	if this.Wifi_ != nil {
+		return this.Wifi_.BSSID
+	} else {
+		return this.BSSID_
+	}
+}
+
+func (this *deviceinfo) SignalLevel() int {
+	if this.Wifi_ != nil {
+		return this.Wifi_.SignalLevel
+	} else {
+		return this.SignalLevel_
+	}
+}
+
+func (this *deviceinfo) NoiseLevel() int {
+	if this.Wifi_ != nil {
+		return this.Wifi_.NoiseLevel
+	} else {
+		return this.NoiseLevel_
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+func (this *deviceinfo) String() string {
+	return fmt.Sprintf("<googlecast.DeviceInfo>{ name=%v version=%v revision=%v build=%v uptime=%v mac=%v timezone=%v locale=%v ip=%v ssid=%v ethernet=%v }",
+		strconv.Quote(this.Name_), strconv.Quote(this.Version()), strconv.Quote(this.CastBuildRevision()), strconv.Quote(this.BuildVersion()), this.Uptime().Truncate(time.Second),
+		this.MacAddress(), strconv.Quote(this.Timezone()), strconv.Quote(this.Locale()), this.IPAddress(), strconv.Quote(this.SSID()), this.EthernetConnected())
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// setupURL returns the URL for a path on the setup API of the device
+func (this *castdevice) setupURL(path string) (string, error) {
+	port := this.setupPort
+	if port == 0 {
+		port = SETUP_PORT
+	}
+	if ip, err := this.addr(gopi.RPC_FLAG_INET_V4 | gopi.RPC_FLAG_INET_V6); err != nil {
+		return "", err
+	} else {
+		return "http://" + net.JoinHostPort(ip.String(), fmt.Sprint(port)) + path, nil
+	}
+}
+
+// setupRequest sends a request to the setup API, encoding the request
+// body (if not nil) and decoding the response (if not nil) as JSON
+func setupRequest(ctx context.Context, method, url string, request, response interface{}) error {
+	body := new(bytes.Buffer)
+	if request != nil {
+		if err := json.NewEncoder(body).Encode(request); err != nil {
+			return err
+		}
+	}
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return err
+	} else if request != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if resp, err := http.DefaultClient.Do(req); err != nil {
+		return err
+	} else {
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("%v: %v", url, resp.Status)
+		} else if response == nil {
+			return nil
+		} else if err := json.NewDecoder(resp.Body).Decode(response); err != nil {
+			return fmt.Errorf("%v: %w", url, err)
+		}
+	}
+
+	// Success
+	return nil
+}
@@ -0,0 +1,420 @@
+package googlecast
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	// Frameworks
+	googlecast "github.com/djthorpe/googlecast"
+	gopi "github.com/djthorpe/gopi"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// setuprequest is a request received by the setup API
+type setuprequest struct {
+	method string
+	path   string
+	query  url.Values
+	body   string
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// EUREKA INFO
+
+const (
+	EUREKA_INFO = `{
+		"bssid": "aa:bb:cc:dd:ee:ff",
+		"build_version": "175638",
+		"cast_build_revision": "1.42.175638",
+		"connected": true,
+		"ethernet_connected": false,
+		"ip_address": "192.168.1.20",
+		"locale": "en-GB",
+		"mac_address": "F4:F5:D8:00:11:22",
+		"name": "Kitchen speaker",
+		"noise_level": -92,
+		"signal_level": -45,
+		"ssid": "home",
+		"timezone": "Europe/London",
+		"uptime": 3661.5,
+		"version": 12
+	}`
+	EUREKA_INFO_DETAIL = `{
+		"build_info": {
+			"build_type": 2,
+			"cast_build_revision": "1.56.281627",
+			"cast_control_version": 1,
+			"preview_channel_state": 0,
+			"release_track": "stable-channel",
+			"system_build_number": "281627"
+		},
+		"device_info": {
+			"cloud_device_id": "0123456789ABCDEF0123456789ABCDEF",
+			"mac_address": "F4:F5:D8:33:44:55",
+			"manufacturer": "Google Inc.",
+			"model_name": "Chromecast",
+			"product_name": "chorizo",
+			"uptime": 7322.25
+		},
+		"name": "Living room TV",
+		"net": {
+			"ethernet_connected": true,
+			"ip_address": "192.168.1.30",
+			"online": true
+		},
+		"settings": {
+			"country_code": "GB",
+			"locale": "en-GB",
+			"network_standby": 0,
+			"timezone": "Europe/London",
+			"wake_on_cast": 1
+		},
+		"version": 12,
+		"wifi": {
+			"bssid": "11:22:33:44:55:66",
+			"has_ever_been_connected": true,
+			"noise_level": -90,
+			"signal_level": -60,
+			"ssid": "upstairs",
+			"wpa_configured": true
+		}
+	}`
+)
+
+func TestDeviceInfo_000(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != SETUP_PATH_INFO {
+			http.NotFound(w, r)
+		} else {
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(EUREKA_INFO))
+		}
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	if info, err := getDeviceInfo(ctx, server.URL+SETUP_PATH_INFO); err != nil {
+		t.Fatal(err)
+	} else {
+		if info.Name() != "Kitchen speaker" {
+			t.Error("Unexpected name", info.Name())
+		}
+		if info.Version() != "12" {
+			t.Error("Unexpected version", info.Version())
+		}
+		if info.CastBuildRevision() != "1.42.175638" {
+			t.Error("Unexpected cast build revision", info.CastBuildRevision())
+		}
+		if info.BuildVersion() != "175638" {
+			t.Error("Unexpected build version", info.BuildVersion())
+		}
+		if info.Uptime() != 3661500*time.Millisecond {
+			t.Error("Unexpected uptime", info.Uptime())
+		}
+		if info.MacAddress() != "F4:F5:D8:00:11:22" {
+			t.Error("Unexpected mac address", info.MacAddress())
+		}
+		if info.Timezone() != "Europe/London" {
+			t.Error("Unexpected timezone", info.Timezone())
+		}
+		if info.Locale() != "en-GB" {
+			t.Error("Unexpected locale", info.Locale())
+		}
+		if info.IPAddress().String() != "192.168.1.20" {
+			t.Error("Unexpected ip address", info.IPAddress())
+		}
+		if info.SSID() != "home" || info.BSSID() != "aa:bb:cc:dd:ee:ff" {
+			t.Error("Unexpected ssid", info.SSID(), info.BSSID())
+		}
+		if info.EthernetConnected() != false || info.SignalLevel() != -45 || info.NoiseLevel() != -92 {
+			t.Error("Unexpected network settings", info)
+		}
+		t.Log(info)
+	}
+}
+
+func TestDeviceInfo_001(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	if _, err := getDeviceInfo(ctx, server.URL+SETUP_PATH_INFO); err == nil {
+		t.Error("Expected error for missing setup API")
+	}
+}
+
+func TestDeviceInfo_002(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != SETUP_PATH_INFO {
+			http.NotFound(w, r)
+		} else if r.URL.Query().Get("options") != "detail" || r.URL.Query().Get("params") == "" {
+			// Firmware omits nested fields without the detail option
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{ "name": "Living room TV", "version": 12 }`))
+		} else {
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(EUREKA_INFO_DETAIL))
+		}
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	if info, err := getDeviceInfo(ctx, server.URL+SETUP_PATH_INFO+SETUP_QUERY_INFO); err != nil {
+		t.Fatal(err)
+	} else {
+		if info.Name() != "Living room TV" {
+			t.Error("Unexpected name", info.Name())
+		}
+		if info.Version() != "12" {
+			t.Error("Unexpected version", info.Version())
+		}
+		if info.CastBuildRevision() != "1.56.281627" {
+			t.Error("Unexpected cast build revision", info.CastBuildRevision())
+		}
+		if info.BuildVersion() != "281627" {
+			t.Error("Unexpected build version", info.BuildVersion())
+		}
+		if info.Uptime() != 7322250*time.Millisecond {
+			t.Error("Unexpected uptime", info.Uptime())
+		}
+		if info.MacAddress() != "F4:F5:D8:33:44:55" {
+			t.Error("Unexpected mac address", info.MacAddress())
+		}
+		if info.Timezone() != "Europe/London" {
+			t.Error("Unexpected timezone", info.Timezone())
+		}
+		if info.Locale() != "en-GB" {
+			t.Error("Unexpected locale", info.Locale())
+		}
+		if info.IPAddress().String() != "192.168.1.30" {
+			t.Error("Unexpected ip address", info.IPAddress())
+		}
+		if info.SSID() != "upstairs" || info.BSSID() != "11:22:33:44:55:66" {
+			t.Error("Unexpected ssid", info.SSID(), info.BSSID())
+		}
+		if info.EthernetConnected() != true || info.SignalLevel() != -60 || info.NoiseLevel() != -90 {
+			t.Error("Unexpected network settings", info)
+		}
+		t.Log(info)
+	}
+}
+
+func TestDeviceInfo_003(t *testing.T) {
+	// Device info is requested from the setup API of the device
+	server, requests := testSetupServer(t)
+	defer server.Close()
+	device := testSetupDevice(t, server)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	if info, err := device.Info(ctx); err != nil {
+		t.Fatal(err)
+	} else if info.Name() != "Kitchen speaker" {
+		t.Error("Unexpected name", info.Name())
+	} else if request := <-requests; request.method != http.MethodGet || request.path != SETUP_PATH_INFO || request.query.Get("options") != "detail" {
+		t.Error("Unexpected request", request)
+	}
+}
+
+func TestSetupURL_000(t *testing.T) {
+	tests := []struct {
+		ip4      []net.IP
+		ip6      []net.IP
+		port     uint
+		expected string
+	}{
+		{[]net.IP{net.IPv4(192, 168, 1, 20)}, nil, 0, "http://192.168.1.20:8008/setup/eureka_info"},
+		{[]net.IP{net.IPv4(192, 168, 1, 20)}, []net.IP{net.ParseIP("fe80::1")}, 0, "http://192.168.1.20:8008/setup/eureka_info"},
+		{nil, []net.IP{net.ParseIP("fe80::1")}, 0, "http://[fe80::1]:8008/setup/eureka_info"},
+		{[]net.IP{net.IPv4(127, 0, 0, 1)}, nil, 8443, "http://127.0.0.1:8443/setup/eureka_info"},
+	}
+	for _, test := range tests {
+		device := NewDevice(&servicerecord{Service_: SERVICE_TYPE_GOOGLECAST, IP4_: test.ip4, IP6_: test.ip6})
+		device.setupPort = test.port
+		if value, err := device.setupURL(SETUP_PATH_INFO); err != nil {
+			t.Error(err)
+		} else if value != test.expected {
+			t.Error("Unexpected url", value, "expected", test.expected)
+		}
+	}
+
+	// Device without an address
+	device := NewDevice(&servicerecord{Service_: SERVICE_TYPE_GOOGLECAST})
+	if _, err := device.setupURL(SETUP_PATH_INFO); errors.Is(err, gopi.ErrNotFound) == false {
+		t.Error("Unexpected error", err)
+	}
+}
+
+func TestSetupURL_001(t *testing.T) {
+	// The address is read while the record is updated
+	device := NewDevice(&servicerecord{Service_: SERVICE_TYPE_GOOGLECAST, IP4_: []net.IP{net.IPv4(192, 168, 1, 20)}})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			device.setRecord(&servicerecord{Service_: SERVICE_TYPE_GOOGLECAST, IP4_: []net.IP{net.IPv4(192, 168, 1, byte(i))}})
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		if _, err := device.setupURL(SETUP_PATH_INFO); err != nil {
+			t.Error(err)
+		}
+	}
+	<-done
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// DEVICE MANAGEMENT
+
+func TestSetup_000(t *testing.T) {
+	// Management is disabled unless enabled
+	server, requests := testSetupServer(t)
+	defer server.Close()
+	this := testCast(t)
+	device := testSetupDevice(t, server)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	for _, err := range []error{
+		this.Reboot(ctx, device),
+		this.FactoryReset(ctx, device, device.Id()),
+		this.SetName(ctx, device, "Kitchen speaker"),
+		this.SetTimezone(ctx, device, "Europe/London"),
+		this.SetLocale(ctx, device, "en-GB"),
+	} {
+		if errors.Is(err, googlecast.ErrManagementDisabled) == false {
+			t.Error("Unexpected error", err)
+		}
+	}
+	if len(requests) != 0 {
+		t.Error("Unexpected requests", len(requests))
+	}
+}
+
+func TestSetup_001(t *testing.T) {
+	// Factory reset requires the device identifier as confirmation
+	server, requests := testSetupServer(t)
+	defer server.Close()
+	this := testCast(t)
+	this.manage = true
+	device := testSetupDevice(t, server)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	for _, confirm := range []string{"", "a2", "A1"} {
+		if err := this.FactoryReset(ctx, device, confirm); errors.Is(err, gopi.ErrBadParameter) == false {
+			t.Error("Unexpected error", err, "for confirmation", strconv.Quote(confirm))
+		}
+	}
+	if len(requests) != 0 {
+		t.Error("Unexpected requests", len(requests))
+	}
+	if err := this.FactoryReset(ctx, device, device.Id()); err != nil {
+		t.Error(err)
+	} else if request := <-requests; request.method != http.MethodPost || request.path != SETUP_PATH_REBOOT || request.body != `{"params":"fdr"}` {
+		t.Error("Unexpected request", request)
+	}
+}
+
+func TestSetup_002(t *testing.T) {
+	// Management requests are posted with a JSON body
+	server, requests := testSetupServer(t)
+	defer server.Close()
+	this := testCast(t)
+	this.manage = true
+	device := testSetupDevice(t, server)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	tests := []struct {
+		name string
+		call func() error
+		path string
+		body string
+	}{
+		{"reboot", func() error { return this.Reboot(ctx, device) }, SETUP_PATH_REBOOT, `{"params":"now"}`},
+		{"name", func() error { return this.SetName(ctx, device, "Kitchen speaker") }, SETUP_PATH_SET_INFO, `{"name":"Kitchen speaker"}`},
+		{"locale", func() error { return this.SetLocale(ctx, device, "en-GB") }, SETUP_PATH_SET_INFO, `{"locale":"en-GB"}`},
+		{"timezone", func() error { return this.SetTimezone(ctx, device, "Europe/London") }, SETUP_PATH_SET_INFO, `{"timezone":"Europe/London"}`},
+	}
+	for _, test := range tests {
+		if err := test.call(); err != nil {
+			t.Errorf("%v: %v", test.name, err)
+		} else if request := <-requests; request.method != http.MethodPost || request.path != test.path || request.body != test.body {
+			t.Errorf("%v: Unexpected request %v", test.name, request)
+		}
+	}
+
+	// Empty or invalid values are not sent
+	for _, err := range []error{
+		this.SetName(ctx, device, ""),
+		this.SetLocale(ctx, device, ""),
+		this.SetTimezone(ctx, device, "Europe/Nowhere"),
+	} {
+		if errors.Is(err, gopi.ErrBadParameter) == false {
+			t.Error("Unexpected error", err)
+		}
+	}
+	if len(requests) != 0 {
+		t.Error("Unexpected requests", len(requests))
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// UTILS
+
+// testSetupServer returns a setup API which records requests
+func testSetupServer(t *testing.T) (*httptest.Server, chan setuprequest) {
+	t.Helper()
+	requests := make(chan setuprequest, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		requests <- setuprequest{r.Method, r.URL.Path, r.URL.Query(), strings.TrimSpace(string(body))}
+		if r.URL.Path == SETUP_PATH_INFO {
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(EUREKA_INFO))
+		}
+	}))
+	return server, requests
+}
+
+// testSetupDevice returns a device with the setup API of a server
+func testSetupDevice(t *testing.T, server *httptest.Server) *castdevice {
+	t.Helper()
+	if addr, err := url.Parse(server.URL); err != nil {
+		t.Fatal(err)
+		return nil
+	} else if port, err := strconv.ParseUint(addr.Port(), 10, 32); err != nil {
+		t.Fatal(err)
+		return nil
+	} else {
+		device := NewDevice(&servicerecord{
+			Service_: SERVICE_TYPE_GOOGLECAST,
+			Text_:    []string{"id=a1", "fn=Kitchen speaker"},
+			IP4_:     []net.IP{net.ParseIP(addr.Hostname())},
+		})
+		device.setupPort = uint(port)
+		return device
+	}
+}
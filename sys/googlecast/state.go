@@ -0,0 +1,72 @@
+/*
+	Go Language Raspberry Pi Interface
+	(c) Copyright David Thorpe 2019
+	All Rights Reserved
+	Documentation http://djthorpe.github.io/gopi/
+	For Licensing and Usage information, please see LICENSE.md
+*/
+
+package googlecast
+
+import (
+	"fmt"
+
+	// Frameworks
+	googlecast "github.com/djthorpe/googlecast"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// caststate is a snapshot of channel state. The application, volume
+// and media values are replaced rather than modified by the channel,
+// so the snapshot does not change once taken
+type caststate struct {
+	app     *application
+	volume  *volume
+	media   *media
+	input   bool
+	standby bool
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// IMPLEMENTATION
+
+func (this *caststate) Application() googlecast.Application {
+	if this.app == nil {
+		return nil
+	} else {
+		return this.app
+	}
+}
+
+func (this *caststate) Volume() googlecast.Volume {
+	if this.volume == nil {
+		return nil
+	} else {
+		return this.volume
+	}
+}
+
+func (this *caststate) Media() googlecast.Media {
+	if this.media == nil {
+		return nil
+	} else {
+		return this.media
+	}
+}
+
+func (this *caststate) ActiveInput() bool {
+	return this.input
+}
+
+func (this *caststate) StandBy() bool {
+	return this.standby
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+func (this *caststate) String() string {
+	return fmt.Sprintf("<googlecast.State>{ app=%v volume=%v media=%v active_input=%v standby=%v }", this.app, this.volume, this.media, this.input, this.standby)
+}
@@ -0,0 +1,372 @@
+/*
+	Go Language Raspberry Pi Interface
+	(c) Copyright David Thorpe 2019
+	All Rights Reserved
+	Documentation http://djthorpe.github.io/gopi/
+	For Licensing and Usage information, please see LICENSE.md
+*/
+
+package googlecast
+
+import (
+	"path"
+	"sync"
+	"time"
+
+	// Frameworks
+	googlecast "github.com/djthorpe/googlecast"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+type subscribers struct {
+	sync.Mutex
+	subscribers map[<-chan googlecast.Event]*subscriber
+
+	// Events are emitted in sequence, and the most recent
+	// events are retained in a ring buffer
+	emit    sync.Mutex
+	seq     uint64
+	size    int
+	history []googlecast.Event
+	next    int
+}
+
+// stamper is implemented by events which can be stamped with
+// a sequence number and timestamp
+type stamper interface {
+	stamp(uint64, time.Time)
+}
+
+type subscriber struct {
+	sync.Mutex
+	googlecast.SubscribeOptions
+
+	C      chan googlecast.Event
+	done   chan struct{}
+	closed bool
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// SUBSCRIBE AND UNSUBSCRIBE
+
+func (this *cast) SubscribeEvents(options googlecast.SubscribeOptions) <-chan googlecast.Event {
+	this.log.Debug2("<googlecast.SubscribeEvents>{ options=%+v }", options)
+	return this.subs.Subscribe(options, this.stateEvents)
+}
+
+func (this *cast) UnsubscribeEvents(C <-chan googlecast.Event) {
+	this.log.Debug2("<googlecast.UnsubscribeEvents>{ }")
+	this.subs.Unsubscribe(C)
+}
+
+// emit sends an event to subscribers with a matching filter
+func (this *cast) emit(evt googlecast.Event) {
+	this.subs.Emit(evt)
+}
+
+// emitDevice emits a device event
+func (this *cast) emitDevice(type_ googlecast.EventType, device *castdevice) {
+	this.emit(&castevent{type_: type_, source_: this, device_: this.deviceFor(device)})
+}
+
+// stateEvents returns events which describe the current state: an
+// added event for each device, and events for the state of each
+// connected channel
+func (this *cast) stateEvents() []googlecast.Event {
+	this.Lock()
+	defer this.Unlock()
+
+	events := make([]googlecast.Event, 0, len(this.devices))
+	for _, device := range this.devices {
+		if device.Verified() == false {
+			continue
+		}
+		events = append(events, &castevent{type_: googlecast.CAST_EVENT_DEVICE_ADDED, source_: this, device_: this.wrap(device)})
+	}
+	for channel, device := range this.channels {
+		state := channel.state()
+		types := []googlecast.EventType{googlecast.CAST_EVENT_CHANNEL_CONNECT}
+		if state.app != nil {
+			types = append(types, googlecast.CAST_EVENT_APPLICATION_UPDATED)
+		}
+		if state.volume != nil {
+			types = append(types, googlecast.CAST_EVENT_VOLUME_UPDATED)
+		}
+		if state.media != nil {
+			types = append(types, googlecast.CAST_EVENT_MEDIA_UPDATED)
+		}
+		for _, type_ := range types {
+			events = append(events, &castevent{type_: type_, source_: this, device_: this.wrap(device), channel_: channel, state_: state})
+		}
+	}
+	return events
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// SUBSCRIBERS
+
+// Subscribe returns a channel for events which match a filter. Any
+// replayed events are buffered before the channel is returned, and
+// state is called to return events for the current state
+func (this *subscribers) Subscribe(options googlecast.SubscribeOptions, state func() []googlecast.Event) <-chan googlecast.Event {
+	if options.Buffer <= 0 {
+		options.Buffer = DEFAULT_EVENT_BUFFER
+	}
+	sub := &subscriber{
+		SubscribeOptions: options,
+		done:             make(chan struct{}),
+	}
+
+	// Prevent events being emitted until the subscriber is added
+	this.emit.Lock()
+	defer this.emit.Unlock()
+
+	// Replay the current state when events after the sequence number
+	// are no longer retained
+	mode := options.Replay
+	if mode == googlecast.CAST_REPLAY_SEQUENCE && this.covers(options.Sequence) == false {
+		mode = googlecast.CAST_REPLAY_STATE
+	}
+
+	replay := make([]googlecast.Event, 0)
+	switch mode {
+	case googlecast.CAST_REPLAY_STATE:
+		// State events are stamped with the sequence number the state
+		// was captured at, so resuming from any of them replays
+		// every event emitted after the capture
+		seq, now, events := this.capture(state)
+		for _, evt := range events {
+			if evt_, ok := evt.(stamper); ok {
+				evt_.stamp(seq, now)
+			}
+			replay = append(replay, evt)
+		}
+	case googlecast.CAST_REPLAY_SEQUENCE:
+		for _, evt := range this.retained() {
+			if evt.Sequence() > options.Sequence {
+				replay = append(replay, evt)
+			}
+		}
+	}
+
+	// Buffer replayed events, reserving space for the overflow event
+	// when the subscription is closed on overflow
+	capacity := options.Buffer + len(replay)
+	if options.Overflow == googlecast.CAST_OVERFLOW_CLOSE {
+		capacity++
+	}
+	sub.C = make(chan googlecast.Event, capacity)
+	for _, evt := range replay {
+		if sub.Matches(evt) {
+			sub.C <- evt
+		}
+	}
+
+	this.Lock()
+	defer this.Unlock()
+	if this.subscribers == nil {
+		this.subscribers = make(map[<-chan googlecast.Event]*subscriber)
+	}
+	this.subscribers[sub.C] = sub
+	return sub.C
+}
+
+func (this *subscribers) Unsubscribe(C <-chan googlecast.Event) {
+	this.Lock()
+	sub, exists := this.subscribers[C]
+	delete(this.subscribers, C)
+	this.Unlock()
+	if exists {
+		sub.Close()
+	}
+}
+
+// Emit stamps an event with a sequence number, retains it and
+// sends it to subscribers which match the event
+func (this *subscribers) Emit(evt googlecast.Event) {
+	this.emit.Lock()
+	defer this.emit.Unlock()
+
+	this.seq++
+	if evt_, ok := evt.(stamper); ok {
+		evt_.stamp(this.seq, time.Now())
+	}
+	this.retain(evt)
+
+	this.Lock()
+	subscribers := make([]*subscriber, 0, len(this.subscribers))
+	for _, sub := range this.subscribers {
+		subscribers = append(subscribers, sub)
+	}
+	this.Unlock()
+
+	for _, sub := range subscribers {
+		if sub.Matches(evt) {
+			sub.Send(evt)
+		}
+	}
+}
+
+// retain adds an event to the ring buffer
+func (this *subscribers) retain(evt googlecast.Event) {
+	if this.size <= 0 {
+		return
+	} else if len(this.history) < this.size {
+		this.history = append(this.history, evt)
+	} else {
+		this.history[this.next] = evt
+	}
+	this.next = (this.next + 1) % this.size
+}
+
+// capture returns the current state with the sequence number and
+// time it was captured at. The emit lock should be held
+func (this *subscribers) capture(state func() []googlecast.Event) (uint64, time.Time, []googlecast.Event) {
+	seq, now := this.seq, time.Now()
+	return seq, now, state()
+}
+
+// covers returns true if all events after a sequence number are
+// retained, and false if the sequence number was not issued
+func (this *subscribers) covers(seq uint64) bool {
+	if seq == this.seq {
+		return true
+	} else if seq > this.seq {
+		return false
+	} else if events := this.retained(); len(events) == 0 {
+		return false
+	} else {
+		return events[0].Sequence() <= seq+1
+	}
+}
+
+// retained returns retained events in sequence
+func (this *subscribers) retained() []googlecast.Event {
+	if len(this.history) < this.size {
+		return this.history
+	}
+	events := make([]googlecast.Event, 0, len(this.history))
+	events = append(events, this.history[this.next:]...)
+	events = append(events, this.history[:this.next]...)
+	return events
+}
+
+func (this *subscribers) Close() {
+	this.Lock()
+	subscribers := this.subscribers
+	this.subscribers = nil
+	this.Unlock()
+	for _, sub := range subscribers {
+		sub.Close()
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// SUBSCRIBER
+
+// Matches returns true if the event matches the subscriber filter
+func (this *subscriber) Matches(evt googlecast.Event) bool {
+	if len(this.Types) > 0 {
+		match := false
+		for _, type_ := range this.Types {
+			if evt.Type() == type_ {
+				match = true
+			}
+		}
+		if match == false {
+			return false
+		}
+	}
+	if len(this.DeviceIds) == 0 && len(this.Names) == 0 {
+		return true
+	}
+	device := evt.Device()
+	if device == nil {
+		return false
+	}
+	if len(this.DeviceIds) > 0 {
+		match := false
+		for _, id := range this.DeviceIds {
+			if device.Id() == id {
+				match = true
+			} else if match_, err := path.Match(id, device.Id()); err == nil && match_ {
+				match = true
+			}
+		}
+		if match == false {
+			return false
+		}
+	}
+	if len(this.Names) > 0 {
+		match := false
+		for _, name := range this.Names {
+			if device.Name() == name {
+				match = true
+			} else if match_, err := path.Match(name, device.Name()); err == nil && match_ {
+				match = true
+			}
+		}
+		if match == false {
+			return false
+		}
+	}
+	return true
+}
+
+// Send an event to the subscriber, applying the overflow policy
+// when the buffer is full
+func (this *subscriber) Send(evt googlecast.Event) {
+	this.Lock()
+	defer this.Unlock()
+	if this.closed {
+		return
+	}
+	switch this.Overflow {
+	case googlecast.CAST_OVERFLOW_BLOCK:
+		select {
+		case this.C <- evt:
+		case <-this.done:
+		}
+	case googlecast.CAST_OVERFLOW_DROP_NEWEST:
+		select {
+		case this.C <- evt:
+		default:
+		}
+	case googlecast.CAST_OVERFLOW_CLOSE:
+		if len(this.C) < cap(this.C)-1 {
+			this.C <- evt
+		} else {
+			// End the subscription with an overflow event
+			this.C <- &casterrorevent{err: googlecast.ErrOverflow}
+			this.closed = true
+			close(this.C)
+		}
+	default:
+		for {
+			select {
+			case this.C <- evt:
+				return
+			default:
+				// Discard the oldest event and try again
+				select {
+				case <-this.C:
+				default:
+				}
+			}
+		}
+	}
+}
+
+// Close the subscriber, unblocking any send in progress. The
+// channel is already closed when the subscriber overflowed
+func (this *subscriber) Close() {
+	close(this.done)
+	this.Lock()
+	defer this.Unlock()
+	if this.closed == false {
+		this.closed = true
+		close(this.C)
+	}
+}
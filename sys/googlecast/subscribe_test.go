@@ -0,0 +1,281 @@
+package googlecast
+
+import (
+	"testing"
+	"time"
+
+	// Frameworks
+	googlecast "github.com/djthorpe/googlecast"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// RING BUFFER
+
+func TestSubscribe_000(t *testing.T) {
+	// Retained events wrap around in sequence
+	subs := &subscribers{size: 3}
+	for i := 0; i < 5; i++ {
+		subs.Emit(&castevent{type_: googlecast.CAST_EVENT_VOLUME_UPDATED})
+	}
+	if events := subs.retained(); len(events) != 3 {
+		t.Fatal("Unexpected number of retained events", len(events))
+	} else {
+		for i, evt := range events {
+			if evt.Sequence() != uint64(i+3) {
+				t.Error("Unexpected sequence", evt.Sequence(), "expected", i+3)
+			}
+		}
+	}
+	for seq, expected := range map[uint64]bool{0: false, 1: false, 2: true, 4: true, 5: true, 6: false} {
+		if subs.covers(seq) != expected {
+			t.Error("Unexpected covers for sequence", seq, "expected", expected)
+		}
+	}
+}
+
+func TestSubscribe_001(t *testing.T) {
+	// Retained events without wraparound
+	subs := &subscribers{size: 3}
+	if subs.covers(0) == false {
+		t.Error("Expected covers for sequence 0 with no events")
+	}
+	subs.Emit(&castevent{type_: googlecast.CAST_EVENT_VOLUME_UPDATED})
+	subs.Emit(&castevent{type_: googlecast.CAST_EVENT_VOLUME_UPDATED})
+	if events := subs.retained(); len(events) != 2 {
+		t.Fatal("Unexpected number of retained events", len(events))
+	} else if events[0].Sequence() != 1 || events[1].Sequence() != 2 {
+		t.Error("Unexpected sequence", events[0].Sequence(), events[1].Sequence())
+	} else if subs.covers(0) == false {
+		t.Error("Expected covers for sequence 0")
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// REPLAY
+
+func TestSubscribe_002(t *testing.T) {
+	// Resume inside the retained window replays events after the sequence
+	subs := &subscribers{size: 3}
+	for i := 0; i < 5; i++ {
+		subs.Emit(&castevent{type_: googlecast.CAST_EVENT_VOLUME_UPDATED})
+	}
+	C := subs.Subscribe(googlecast.SubscribeOptions{Replay: googlecast.CAST_REPLAY_SEQUENCE, Sequence: 3}, subscribeState(t))
+	defer subs.Unsubscribe(C)
+	for _, expected := range []uint64{4, 5} {
+		if evt := subscribeNext(t, C); evt.Sequence() != expected {
+			t.Error("Unexpected sequence", evt.Sequence(), "expected", expected)
+		}
+	}
+	subs.Emit(&castevent{type_: googlecast.CAST_EVENT_MEDIA_UPDATED})
+	if evt := subscribeNext(t, C); evt.Sequence() != 6 || evt.Type() != googlecast.CAST_EVENT_MEDIA_UPDATED {
+		t.Error("Unexpected event", evt)
+	}
+}
+
+func TestSubscribe_003(t *testing.T) {
+	// Resume outside the retained window resends the state, stamped
+	// with the sequence it was captured at
+	subs := &subscribers{size: 3}
+	for i := 0; i < 5; i++ {
+		subs.Emit(&castevent{type_: googlecast.CAST_EVENT_VOLUME_UPDATED})
+	}
+	C := subs.Subscribe(googlecast.SubscribeOptions{Replay: googlecast.CAST_REPLAY_SEQUENCE, Sequence: 1}, func() []googlecast.Event {
+		return []googlecast.Event{
+			&castevent{type_: googlecast.CAST_EVENT_DEVICE_ADDED},
+			&castevent{type_: googlecast.CAST_EVENT_CHANNEL_CONNECT},
+		}
+	})
+	defer subs.Unsubscribe(C)
+	for _, expected := range []googlecast.EventType{googlecast.CAST_EVENT_DEVICE_ADDED, googlecast.CAST_EVENT_CHANNEL_CONNECT} {
+		if evt := subscribeNext(t, C); evt.Type() != expected {
+			t.Error("Unexpected event", evt.Type(), "expected", expected)
+		} else if evt.Sequence() != 5 {
+			t.Error("Unexpected sequence", evt.Sequence(), "expected 5")
+		} else if evt.Timestamp().IsZero() {
+			t.Error("Expected timestamp")
+		}
+	}
+
+	// Resuming from the state sequence does not resend the state
+	subs.Emit(&castevent{type_: googlecast.CAST_EVENT_MEDIA_UPDATED})
+	if evt := subscribeNext(t, C); evt.Sequence() != 6 {
+		t.Error("Unexpected sequence", evt.Sequence(), "expected 6")
+	}
+	D := subs.Subscribe(googlecast.SubscribeOptions{Replay: googlecast.CAST_REPLAY_SEQUENCE, Sequence: 5}, subscribeState(t))
+	defer subs.Unsubscribe(D)
+	if evt := subscribeNext(t, D); evt.Sequence() != 6 || evt.Type() != googlecast.CAST_EVENT_MEDIA_UPDATED {
+		t.Error("Unexpected event", evt)
+	}
+}
+
+func TestSubscribe_004(t *testing.T) {
+	// Resume from a sequence which was not issued resends the state
+	subs := &subscribers{size: 3}
+	subs.Emit(&castevent{type_: googlecast.CAST_EVENT_VOLUME_UPDATED})
+	C := subs.Subscribe(googlecast.SubscribeOptions{Replay: googlecast.CAST_REPLAY_SEQUENCE, Sequence: 10}, func() []googlecast.Event {
+		return []googlecast.Event{&castevent{type_: googlecast.CAST_EVENT_DEVICE_ADDED}}
+	})
+	defer subs.Unsubscribe(C)
+	if evt := subscribeNext(t, C); evt.Type() != googlecast.CAST_EVENT_DEVICE_ADDED || evt.Sequence() != 1 {
+		t.Error("Unexpected event", evt)
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// OVERFLOW
+
+func TestSubscribe_005(t *testing.T) {
+	// Drop oldest events when the buffer is full
+	subs := &subscribers{}
+	C := subs.Subscribe(googlecast.SubscribeOptions{Buffer: 2, Overflow: googlecast.CAST_OVERFLOW_DROP_OLDEST}, nil)
+	defer subs.Unsubscribe(C)
+	for i := 0; i < 4; i++ {
+		subs.Emit(&castevent{type_: googlecast.CAST_EVENT_VOLUME_UPDATED})
+	}
+	for _, expected := range []uint64{3, 4} {
+		if evt := subscribeNext(t, C); evt.Sequence() != expected {
+			t.Error("Unexpected sequence", evt.Sequence(), "expected", expected)
+		}
+	}
+}
+
+func TestSubscribe_006(t *testing.T) {
+	// Drop newest events when the buffer is full
+	subs := &subscribers{}
+	C := subs.Subscribe(googlecast.SubscribeOptions{Buffer: 2, Overflow: googlecast.CAST_OVERFLOW_DROP_NEWEST}, nil)
+	defer subs.Unsubscribe(C)
+	for i := 0; i < 4; i++ {
+		subs.Emit(&castevent{type_: googlecast.CAST_EVENT_VOLUME_UPDATED})
+	}
+	for _, expected := range []uint64{1, 2} {
+		if evt := subscribeNext(t, C); evt.Sequence() != expected {
+			t.Error("Unexpected sequence", evt.Sequence(), "expected", expected)
+		}
+	}
+	select {
+	case evt := <-C:
+		t.Error("Unexpected event", evt)
+	default:
+	}
+}
+
+func TestSubscribe_007(t *testing.T) {
+	// Block when the buffer is full until the subscriber reads
+	subs := &subscribers{}
+	C := subs.Subscribe(googlecast.SubscribeOptions{Buffer: 1, Overflow: googlecast.CAST_OVERFLOW_BLOCK}, nil)
+	defer subs.Unsubscribe(C)
+	subs.Emit(&castevent{type_: googlecast.CAST_EVENT_VOLUME_UPDATED})
+	done := make(chan struct{})
+	go func() {
+		subs.Emit(&castevent{type_: googlecast.CAST_EVENT_VOLUME_UPDATED})
+		close(done)
+	}()
+	select {
+	case <-done:
+		t.Fatal("Expected emit to block")
+	case <-time.After(100 * time.Millisecond):
+	}
+	for _, expected := range []uint64{1, 2} {
+		if evt := subscribeNext(t, C); evt.Sequence() != expected {
+			t.Error("Unexpected sequence", evt.Sequence(), "expected", expected)
+		}
+	}
+	<-done
+}
+
+func TestSubscribe_008(t *testing.T) {
+	// Unsubscribe unblocks a blocked emit
+	subs := &subscribers{}
+	C := subs.Subscribe(googlecast.SubscribeOptions{Buffer: 1, Overflow: googlecast.CAST_OVERFLOW_BLOCK}, nil)
+	subs.Emit(&castevent{type_: googlecast.CAST_EVENT_VOLUME_UPDATED})
+	done := make(chan struct{})
+	go func() {
+		subs.Emit(&castevent{type_: googlecast.CAST_EVENT_VOLUME_UPDATED})
+		close(done)
+	}()
+	time.Sleep(100 * time.Millisecond)
+	subs.Unsubscribe(C)
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Expected emit to unblock")
+	}
+}
+
+func TestSubscribe_010(t *testing.T) {
+	// Close the subscription with an overflow event when the buffer is full
+	subs := &subscribers{}
+	C := subs.Subscribe(googlecast.SubscribeOptions{Buffer: 2, Overflow: googlecast.CAST_OVERFLOW_CLOSE}, nil)
+	for i := 0; i < 4; i++ {
+		subs.Emit(&castevent{type_: googlecast.CAST_EVENT_VOLUME_UPDATED})
+	}
+	for _, expected := range []uint64{1, 2} {
+		if evt := subscribeNext(t, C); evt.Sequence() != expected {
+			t.Error("Unexpected sequence", evt.Sequence(), "expected", expected)
+		}
+	}
+	if evt, ok := subscribeNext(t, C).(googlecast.ErrorEvent); ok == false {
+		t.Error("Expected error event")
+	} else if evt.Err() != googlecast.ErrOverflow {
+		t.Error("Unexpected error", evt.Err())
+	}
+	if _, ok := <-C; ok {
+		t.Error("Expected channel to be closed")
+	}
+	subs.Unsubscribe(C)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// FILTER
+
+func TestSubscribe_009(t *testing.T) {
+	// Device identifiers and names match exact values or glob patterns
+	device := &castdevice{txt_: map[string]string{"id": "a1b2c3", "fn": "Kitchen speaker"}}
+	evt := &castevent{type_: googlecast.CAST_EVENT_VOLUME_UPDATED, device_: device}
+	tests := []struct {
+		options  googlecast.SubscribeOptions
+		expected bool
+	}{
+		{googlecast.SubscribeOptions{}, true},
+		{googlecast.SubscribeOptions{DeviceIds: []string{"a1b2c3"}}, true},
+		{googlecast.SubscribeOptions{DeviceIds: []string{"a1*"}}, true},
+		{googlecast.SubscribeOptions{DeviceIds: []string{"b*"}}, false},
+		{googlecast.SubscribeOptions{Names: []string{"Kitchen speaker"}}, true},
+		{googlecast.SubscribeOptions{Names: []string{"Kitchen*"}}, true},
+		{googlecast.SubscribeOptions{Names: []string{"Lounge*"}}, false},
+		{googlecast.SubscribeOptions{DeviceIds: []string{"a1*"}, Names: []string{"Lounge*"}}, false},
+		{googlecast.SubscribeOptions{Types: []googlecast.EventType{googlecast.CAST_EVENT_MEDIA_UPDATED}}, false},
+	}
+	for _, test := range tests {
+		sub := &subscriber{SubscribeOptions: test.options}
+		if sub.Matches(evt) != test.expected {
+			t.Errorf("Unexpected match for %+v, expected %v", test.options, test.expected)
+		}
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// UTILS
+
+// subscribeState returns a state function which should not be called
+func subscribeState(t *testing.T) func() []googlecast.Event {
+	return func() []googlecast.Event {
+		t.Error("Unexpected state replay")
+		return nil
+	}
+}
+
+// subscribeNext returns the next event on a channel, or fails
+func subscribeNext(t *testing.T, C <-chan googlecast.Event) googlecast.Event {
+	t.Helper()
+	select {
+	case evt := <-C:
+		if evt == nil {
+			t.Fatal("Unexpected closed channel")
+		}
+		return evt
+	case <-time.After(time.Second):
+		t.Fatal("Timeout waiting for event")
+	}
+	return nil
+}
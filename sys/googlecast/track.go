@@ -0,0 +1,126 @@
+/*
+	Go Language Raspberry Pi Interface
+	(c) Copyright David Thorpe 2019
+	All Rights Reserved
+	Documentation http://djthorpe.github.io/gopi/
+	For Licensing and Usage information, please see LICENSE.md
+*/
+
+package googlecast
+
+import (
+	// Frameworks
+	googlecast "github.com/djthorpe/googlecast"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+type mediaTrack struct {
+	TrackId          int    `json:"trackId"`
+	Type             string `json:"type"`
+	TrackContentId   string `json:"trackContentId,omitempty"`
+	TrackContentType string `json:"trackContentType,omitempty"`
+	Subtype          string `json:"subtype,omitempty"`
+	Name             string `json:"name,omitempty"`
+	Language         string `json:"language,omitempty"`
+}
+
+type textTrackStyle struct {
+	FontScale       float32 `json:"fontScale,omitempty"`
+	FontFamily      string  `json:"fontFamily,omitempty"`
+	ForegroundColor string  `json:"foregroundColor,omitempty"`
+	BackgroundColor string  `json:"backgroundColor,omitempty"`
+	EdgeType        string  `json:"edgeType,omitempty"`
+	EdgeColor       string  `json:"edgeColor,omitempty"`
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// CONSTANTS
+
+var (
+	trackTypes = map[googlecast.TrackType]string{
+		googlecast.CAST_TRACK_TYPE_TEXT:  "TEXT",
+		googlecast.CAST_TRACK_TYPE_AUDIO: "AUDIO",
+		googlecast.CAST_TRACK_TYPE_VIDEO: "VIDEO",
+	}
+	edgeTypes = map[googlecast.EdgeType]string{
+		googlecast.CAST_EDGE_TYPE_NONE:        "NONE",
+		googlecast.CAST_EDGE_TYPE_OUTLINE:     "OUTLINE",
+		googlecast.CAST_EDGE_TYPE_DROP_SHADOW: "DROP_SHADOW",
+		googlecast.CAST_EDGE_TYPE_RAISED:      "RAISED",
+		googlecast.CAST_EDGE_TYPE_DEPRESSED:   "DEPRESSED",
+	}
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// CONVERT TRACKS
+
+func newMediaTracks(tracks []googlecast.Track) []mediaTrack {
+	if len(tracks) == 0 {
+		return nil
+	}
+	values := make([]mediaTrack, len(tracks))
+	for i, track := range tracks {
+		values[i] = mediaTrack{
+			TrackId:          track.Id,
+			Type:             trackTypes[track.Type],
+			TrackContentId:   track.ContentId,
+			TrackContentType: track.ContentType,
+			Subtype:          track.Subtype,
+			Name:             track.Name,
+			Language:         track.Language,
+		}
+	}
+	return values
+}
+
+func (this mediaTrack) Track() googlecast.Track {
+	track := googlecast.Track{
+		Id:          this.TrackId,
+		ContentId:   this.TrackContentId,
+		ContentType: this.TrackContentType,
+		Subtype:     this.Subtype,
+		Name:        this.Name,
+		Language:    this.Language,
+	}
+	for k, v := range trackTypes {
+		if v == this.Type {
+			track.Type = k
+		}
+	}
+	return track
+}
+
+func newTextTrackStyle(style *googlecast.TextTrackStyle) *textTrackStyle {
+	if style == nil {
+		return nil
+	}
+	return &textTrackStyle{
+		FontScale:       style.FontScale,
+		FontFamily:      style.FontFamily,
+		ForegroundColor: style.ForegroundColor,
+		BackgroundColor: style.BackgroundColor,
+		EdgeType:        edgeTypes[style.EdgeType],
+		EdgeColor:       style.EdgeColor,
+	}
+}
+
+func (this *textTrackStyle) TextTrackStyle() *googlecast.TextTrackStyle {
+	if this == nil {
+		return nil
+	}
+	style := &googlecast.TextTrackStyle{
+		FontScale:       this.FontScale,
+		FontFamily:      this.FontFamily,
+		ForegroundColor: this.ForegroundColor,
+		BackgroundColor: this.BackgroundColor,
+		EdgeColor:       this.EdgeColor,
+	}
+	for k, v := range edgeTypes {
+		if v == this.EdgeType {
+			style.EdgeType = k
+		}
+	}
+	return style
+}
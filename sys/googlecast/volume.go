@@ -10,14 +10,17 @@ package googlecast
 
 import (
 	"fmt"
+	"strconv"
 )
 
 ////////////////////////////////////////////////////////////////////////////////
 // TYPES
 
 type volume struct {
-	Level_ float32 `json:"level,omitempty"`
-	Muted_ bool    `json:"muted"`
+	Level_        float32 `json:"level,omitempty"`
+	Muted_        bool    `json:"muted"`
+	ControlType_  string  `json:"controlType,omitempty"`
+	StepInterval_ float32 `json:"stepInterval,omitempty"`
 }
 
 ////////////////////////////////////////////////////////////////////////////////
@@ -31,8 +34,20 @@ func (this *volume) Muted() bool {
 	return this.Muted_
 }
 
+func (this *volume) ControlType() string {
+	return this.ControlType_
+}
+
+func (this *volume) StepInterval() float32 {
+	return this.StepInterval_
+}
+
 func (this *volume) String() string {
-	return fmt.Sprintf("<googlecast.Volume>{ level=%.2f muted=%v }", this.Level_, this.Muted_)
+	if this.ControlType_ != "" {
+		return fmt.Sprintf("<googlecast.Volume>{ level=%.2f muted=%v control_type=%v step_interval=%.2f }", this.Level_, this.Muted_, strconv.Quote(this.ControlType_), this.StepInterval_)
+	} else {
+		return fmt.Sprintf("<googlecast.Volume>{ level=%.2f muted=%v }", this.Level_, this.Muted_)
+	}
 }
 
 func (this *volume) Equals(other *volume) bool {
@@ -42,5 +57,11 @@ func (this *volume) Equals(other *volume) bool {
 	if this.Muted_ != other.Muted_ {
 		return false
 	}
+	if this.ControlType_ != other.ControlType_ {
+		return false
+	}
+	if this.StepInterval_ != other.StepInterval_ {
+		return false
+	}
 	return true
 }
@@ -0,0 +1,132 @@
+/*
+  Go Language Raspberry Pi Interface
+  (c) Copyright David Thorpe 2019
+  All Rights Reserved
+  Documentation http://djthorpe.github.io/gopi/
+  For Licensing and Usage information, please see LICENSE.md
+*/
+
+package googlecast
+
+import (
+	"fmt"
+	"strconv"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+type (
+	TrackType uint
+	EdgeType  uint
+)
+
+// Track is an audio, video or text track of media. For text tracks
+// the subtype is SUBTITLES, CAPTIONS, DESCRIPTIONS, CHAPTERS or METADATA
+type Track struct {
+	Id          int
+	Type        TrackType
+	ContentId   string
+	ContentType string
+	Subtype     string
+	Name        string
+	Language    string
+}
+
+// TextTrackStyle sets the appearance of text tracks. Colours are
+// in the form #RRGGBBAA and a zero font scale uses the default
+type TextTrackStyle struct {
+	FontScale       float32
+	FontFamily      string
+	ForegroundColor string
+	BackgroundColor string
+	EdgeType        EdgeType
+	EdgeColor       string
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// CONSTANTS
+
+const (
+	CAST_TRACK_TYPE_NONE TrackType = iota
+	CAST_TRACK_TYPE_TEXT
+	CAST_TRACK_TYPE_AUDIO
+	CAST_TRACK_TYPE_VIDEO
+)
+
+const (
+	CAST_EDGE_TYPE_NONE EdgeType = iota
+	CAST_EDGE_TYPE_OUTLINE
+	CAST_EDGE_TYPE_DROP_SHADOW
+	CAST_EDGE_TYPE_RAISED
+	CAST_EDGE_TYPE_DEPRESSED
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// NEW
+
+// NewSubtitleTrack returns a WebVTT subtitle track, which can be
+// attached to media when it is loaded
+func NewSubtitleTrack(id int, url, language, name string) Track {
+	return Track{
+		Id:          id,
+		Type:        CAST_TRACK_TYPE_TEXT,
+		ContentId:   url,
+		ContentType: "text/vtt",
+		Subtype:     "SUBTITLES",
+		Name:        name,
+		Language:    language,
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+func (t TrackType) String() string {
+	switch t {
+	case CAST_TRACK_TYPE_NONE:
+		return "CAST_TRACK_TYPE_NONE"
+	case CAST_TRACK_TYPE_TEXT:
+		return "CAST_TRACK_TYPE_TEXT"
+	case CAST_TRACK_TYPE_AUDIO:
+		return "CAST_TRACK_TYPE_AUDIO"
+	case CAST_TRACK_TYPE_VIDEO:
+		return "CAST_TRACK_TYPE_VIDEO"
+	default:
+		return "[?? Invalid TrackType value]"
+	}
+}
+
+func (t EdgeType) String() string {
+	switch t {
+	case CAST_EDGE_TYPE_NONE:
+		return "CAST_EDGE_TYPE_NONE"
+	case CAST_EDGE_TYPE_OUTLINE:
+		return "CAST_EDGE_TYPE_OUTLINE"
+	case CAST_EDGE_TYPE_DROP_SHADOW:
+		return "CAST_EDGE_TYPE_DROP_SHADOW"
+	case CAST_EDGE_TYPE_RAISED:
+		return "CAST_EDGE_TYPE_RAISED"
+	case CAST_EDGE_TYPE_DEPRESSED:
+		return "CAST_EDGE_TYPE_DEPRESSED"
+	default:
+		return "[?? Invalid EdgeType value]"
+	}
+}
+
+func (this Track) String() string {
+	var parts string
+	if this.ContentType != "" {
+		parts += fmt.Sprintf(" content_type=%v", strconv.Quote(this.ContentType))
+	}
+	if this.Subtype != "" {
+		parts += fmt.Sprintf(" subtype=%v", strconv.Quote(this.Subtype))
+	}
+	if this.Name != "" {
+		parts += fmt.Sprintf(" name=%v", strconv.Quote(this.Name))
+	}
+	if this.Language != "" {
+		parts += fmt.Sprintf(" language=%v", strconv.Quote(this.Language))
+	}
+	return fmt.Sprintf("<googlecast.Track>{ id=%v type=%v%v }", this.Id, this.Type, parts)
+}